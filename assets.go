@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Notes on asset receipts.
+const (
+	assetCarriedOver = "übernommen aus Vorjahr"
+	assetUnpaid      = "unbezahlt"
+)
+
+// AssetEntry describes an asset receipt with depreciation plan and where its input tax is claimed.
+type AssetEntry struct {
+	Receipt      int
+	Date         Date
+	Depreciation Date
+	InputTax     Cents
+	Period       Period // UStVA period of the input tax, nil if none is claimed
+	Note         string
+}
+
+// inputTax returns the input tax of all payments of the receipt.
+func (e *Eur) inputTax(r *Receipt) Cents {
+	var tax Cents
+	for _, p := range r.Payments {
+		if _, ok := e.taxBookingAccounts[p.Account]; ok {
+			continue
+		}
+		for _, acc := range []TaxAccount{p.Incoming, p.Outgoing} {
+			if acc != 0 && acc.IsExpense() {
+				_, percent := e.classify(p, acc)
+				tax += p.getTax(percent)
+			}
+		}
+	}
+	return tax
+}
+
+// checkCarriedOver warns about depreciation plans dated on the first day of the business year, which are only
+// assumed to be carried over: they could also be a purchase on that day.
+func (e *Eur) checkCarriedOver() {
+	for _, r := range e.Receipts {
+		if r.DepreciationDate == nil || r.info.CarriedOver != nil {
+			continue
+		}
+		if r.Date.compare(e.Start) == 0 && r.DepreciationDate.compare(e.Start) >= 0 {
+			log.Printf("WARNING: Receipt #%d with depreciation plan is dated on the first day of the business year (%s) "+
+				"and is therefore considered carried over, without input tax. If it has been bought on that day, "+
+				"set `carriedOver` to false in the sidecar file, otherwise to true.", r.Number, r.Date)
+		}
+	}
+}
+
+// assetData lists all receipts with a depreciation plan. The input tax of an asset is claimed in the period
+// of its purchase, independent of the start of depreciation. Carried over plans do not affect the VAT.
+func assetData(conf *Config, jes []*Eur) []AssetEntry {
+	var entries []AssetEntry
+
+	for _, e := range jes {
+		for _, r := range e.Receipts {
+			if r.DepreciationDate == nil {
+				continue
+			}
+
+			entry := AssetEntry{
+				Receipt:      r.Number,
+				Date:         r.Date,
+				Depreciation: *r.DepreciationDate,
+			}
+
+			switch {
+			case e.carriedOver(r):
+				entry.Note = assetCarriedOver
+			case !e.booked(r):
+				entry.Note = assetUnpaid
+			default:
+				entry.InputTax = e.inputTax(r)
+				if entry.InputTax != 0 {
+					entry.Period = periodOf(r.Date, conf.Filing)
+				}
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// WriteAssets prints the table of asset receipts.
+func WriteAssets(w io.Writer, entries []AssetEntry) {
+	fmt.Fprintf(w, "Beleg\tDatum\t\tAfA ab\t\tVorsteuer\t\tZeitraum\tHinweis\n")
+	for _, e := range entries {
+		period := "-"
+		if e.Period != nil {
+			period = periodLabel(e.Period)
+		}
+		fmt.Fprintf(w, "#%d\t%s\t%s\t%s\t%s\t\t%s\n",
+			e.Receipt, e.Date, e.Depreciation, e.InputTax.Format("%6d,%02d EUR"), period, e.Note)
+	}
+}
+
+// cmdAssets handles
+//
+//	assets <jes.file> [<jes.file>...]
+func cmdAssets(conf *Config, args []string) {
+	if len(args) < 1 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jes := loadJes(conf, args)
+	WriteAssets(os.Stdout, assetData(conf, jes))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAssetPayments(t *testing.T) {
+	e := &Eur{
+		Start:       Date{2024, 1, 1},
+		End:         Date{2024, 12, 31},
+		accountInfo: map[TaxAccount]Account{100: {Number: 100, Percent: 19 * pct}},
+	}
+	e.Receipts = []*Receipt{
+		// bought in December, depreciated from January
+		{Number: 1, Date: Date{2024, 12, 15}, Paid: true, DepreciationDate: &Date{2025, 1, 1},
+			Payments: []*Payment{newPayment(100, 4830, "1190")}},
+		// bought and depreciated in March
+		{Number: 2, Date: Date{2024, 3, 5}, Paid: true, DepreciationDate: &Date{2024, 3, 5},
+			Payments: []*Payment{newPayment(100, 4830, "119")}},
+		// carried over from last year
+		{Number: 3, Date: Date{2024, 1, 1}, Paid: true, DepreciationDate: &Date{2023, 6, 1},
+			Payments: []*Payment{newPayment(100, 4830, "595")}},
+		// not paid yet
+		{Number: 4, Date: Date{2024, 5, 1}, DepreciationDate: &Date{2024, 5, 1},
+			Payments: []*Payment{newPayment(100, 4830, "238")}},
+	}
+	for _, r := range e.Receipts {
+		r.Payments[0].Amount.TaxHandling = "incl"
+	}
+	e.Validate()
+
+	vatData := e.VatData(Year(2024))
+	if got, want := vatData[VatKey{100, Regular}].Tax, Cents(20900); got != want {
+		t.Errorf("input tax = %s, want %s", got, want)
+	}
+
+	got := assetData(&Config{Filing: filingQuarterly}, []*Eur{e})
+	want := []AssetEntry{
+		{1, Date{2024, 12, 15}, Date{2025, 1, 1}, 19000, Quarter{2024, 4}, ""},
+		{2, Date{2024, 3, 5}, Date{2024, 3, 5}, 1900, Quarter{2024, 1}, ""},
+		{3, Date{2024, 1, 1}, Date{2023, 6, 1}, 0, nil, assetCarriedOver},
+		{4, Date{2024, 5, 1}, Date{2024, 5, 1}, 0, nil, assetUnpaid},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("assetData() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("assetData()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAssetCarriedOver(t *testing.T) {
+	accounts := map[TaxAccount]Account{100: {Number: 100, Percent: 19 * pct}}
+
+	// bought in December, depreciated from January
+	prior := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}, accountInfo: accounts}
+	prior.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 12, 15}, Paid: true, DepreciationDate: &Date{2025, 1, 1},
+			Payments: []*Payment{newPayment(100, 4830, "1000")}},
+	}
+	prior.Validate()
+
+	// JES carries the plan over to the next business year, dated on its first day
+	current := &Eur{Start: Date{2025, 1, 1}, End: Date{2025, 12, 31}, accountInfo: accounts}
+	current.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2025, 1, 1}, Paid: true, DepreciationDate: &Date{2025, 1, 1},
+			Payments: []*Payment{newPayment(100, 4830, "1000")}},
+		// bought and depreciated in January
+		{Number: 2, Date: Date{2025, 1, 20}, Paid: true, DepreciationDate: &Date{2025, 1, 20},
+			Payments: []*Payment{newPayment(100, 4830, "100")}},
+	}
+	current.Validate()
+
+	jes := []*Eur{prior, current}
+
+	tests := []struct {
+		period Period
+		want   Cents
+	}{
+		{Month{2024, 12}, 190_00},
+		{Month{2025, 1}, 19_00},
+		{Year(2025), 19_00},
+	}
+	for _, tt := range tests {
+		vatData, _ := mergedVatData(jes, tt.period)
+		if got := vatData[VatKey{100, Regular}].Tax; got != tt.want {
+			t.Errorf("input tax in %s = %s, want %s", tt.period, got, tt.want)
+		}
+	}
+
+	got := assetData(&Config{}, jes)
+	want := []AssetEntry{
+		{1, Date{2024, 12, 15}, Date{2025, 1, 1}, 190_00, Month{2024, 12}, ""},
+		{1, Date{2025, 1, 1}, Date{2025, 1, 1}, 0, nil, assetCarriedOver},
+		{2, Date{2025, 1, 20}, Date{2025, 1, 20}, 19_00, Month{2025, 1}, ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("assetData() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("assetData()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAssetPurchasedOnStart(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	no, yes := false, true
+	tests := []struct {
+		name        string
+		carriedOver *bool
+		inputTax    Cents
+		warning     bool
+	}{
+		{"not marked", nil, 0, true},
+		{"purchase", &no, 190_00, false},
+		{"carried over", &yes, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			// bought on the first day of the business year
+			e := &Eur{Start: Date{2025, 1, 1}, End: Date{2025, 12, 31},
+				accountInfo: map[TaxAccount]Account{100: {Number: 100, Percent: 19 * pct}}}
+			e.Receipts = []*Receipt{
+				{Number: 1, Date: Date{2025, 1, 1}, Paid: true, DepreciationDate: &Date{2025, 1, 1},
+					Payments: []*Payment{newPayment(100, 4830, "1000")},
+					info:     ReceiptInfo{CarriedOver: tt.carriedOver}},
+			}
+			e.Validate()
+			e.checkCarriedOver()
+
+			if got := e.VatData(Month{2025, 1})[VatKey{100, Regular}].Tax; got != tt.inputTax {
+				t.Errorf("input tax = %s, want %s", got, tt.inputTax)
+			}
+			if warned := strings.Contains(buf.String(), "Receipt #1"); warned != tt.warning {
+				t.Errorf("warning = %q, want one: %v", buf.String(), tt.warning)
+			}
+		})
+	}
+}
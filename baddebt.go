@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+)
+
+// negated returns a copy of the payment with the negated value, as used for §17 corrections.
+func (p *Payment) negated() *Payment {
+	n := *p
+	n.Amount.value = -p.getValue()
+	n.Amount.parsed = true
+	return &n
+}
+
+// writtenOff returns whether the receipt has been written off and needs a §17 correction.
+// This only applies under Sollversteuerung: otherwise, an unpaid receipt has never been taxed.
+func (e *Eur) writtenOff(r *Receipt) bool {
+	return e.accrual && !r.info.WrittenOff.IsZero()
+}
+
+// checkWrittenOff validates the write-off dates given in the sidecar file.
+func (e *Eur) checkWrittenOff() {
+	for _, r := range e.Receipts {
+		date := r.info.WrittenOff
+		if date.IsZero() {
+			continue
+		}
+
+		if date.compare(r.Date) < 0 {
+			log.Fatalf("Receipt #%d in '%s' is written off (%s) before its date (%s).", r.Number, e.file, date, r.Date)
+		}
+		if !e.accrual {
+			log.Printf("WARNING: Receipt #%d is written off, which is ignored under Istversteuerung. "+
+				"See `sollversteuerung` in the config.", r.Number)
+		}
+	}
+}
+
+// BadDebt is a §17 correction of an uncollectible receipt.
+type BadDebt struct {
+	Receipt    int
+	Date       Date // of the original receipt
+	WrittenOff Date
+	Amount     Cents // value of the original receipt, the correction is its negation
+}
+
+// badDebts returns the §17 corrections in the given period.
+func (e *Eur) badDebts(period Period) []BadDebt {
+	var result []BadDebt
+	for _, r := range e.Receipts {
+		if e.writtenOff(r) && period.includes(r.info.WrittenOff) {
+			result = append(result, BadDebt{r.Number, r.Date, r.info.WrittenOff, e.value(r)})
+		}
+	}
+	return result
+}
+
+// WriteBadDebts lists the §17 corrections of the period.
+func WriteBadDebts(w io.Writer, badDebts []BadDebt) {
+	for _, b := range badDebts {
+		fmt.Fprintf(w, "    §17 correction #%d (%s), written off %s: %s\n", b.Receipt, b.Date, b.WrittenOff, -b.Amount)
+	}
+}
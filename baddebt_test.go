@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBadDebts(t *testing.T) {
+	newEur := func(accrual bool) *Eur {
+		e := &Eur{
+			Start:       Date{2024, 1, 1},
+			accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+			accrual:     accrual,
+		}
+		e.Receipts = []*Receipt{
+			{Number: 1, Date: Date{2024, 1, 10}, Payments: []*Payment{newPayment(500, 8400, "119")},
+				info: ReceiptInfo{WrittenOff: Date{2024, 3, 15}}},
+			{Number: 2, Date: Date{2024, 1, 20}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "238")}},
+		}
+		for _, r := range e.Receipts {
+			r.Payments[0].Amount.TaxHandling = "incl"
+		}
+		e.Validate()
+		return e
+	}
+
+	tests := []struct {
+		accrual bool
+		period  Period
+		net     Cents
+		tax     Cents
+	}{
+		{true, Month{2024, 1}, 30000, 5700},
+		{true, Month{2024, 3}, -10000, -1900},
+		{true, Year(2024), 20000, 3800},
+		{false, Month{2024, 1}, 20000, 3800},
+		{false, Month{2024, 3}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		e := newEur(tt.accrual)
+		vd := e.VatData(tt.period)[VatKey{500, Regular}]
+		if vd.NetAmount != tt.net || vd.Tax != tt.tax {
+			t.Errorf("VatData(%s, accrual=%v) = %s / %s, want %s / %s",
+				periodLabel(tt.period), tt.accrual, vd.NetAmount, vd.Tax, tt.net, tt.tax)
+		}
+	}
+
+	// the original payment is unchanged
+	e := newEur(true)
+	e.VatData(Year(2024))
+	if got := e.Receipts[0].Payments[0].getValue(); got != 11900 {
+		t.Errorf("original payment = %s, want 119.00 EUR", got)
+	}
+
+	want := []BadDebt{{1, Date{2024, 1, 10}, Date{2024, 3, 15}, 11900}}
+	if got := e.badDebts(Month{2024, 3}); !slices.Equal(got, want) {
+		t.Errorf("badDebts() = %v, want %v", got, want)
+	}
+	if got := newEur(false).badDebts(Month{2024, 3}); len(got) != 0 {
+		t.Errorf("badDebts() under Istversteuerung = %v, want none", got)
+	}
+}
+
+func TestBadDebtsCrossYear(t *testing.T) {
+	accounts := map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}}
+
+	// the receipt is invoiced in 2023 and only written off in 2024
+	prior := &Eur{file: "2023.eux", Start: Date{2023, 1, 1}, End: Date{2023, 12, 31}, accountInfo: accounts, accrual: true}
+	prior.Receipts = []*Receipt{
+		{Number: 7, Date: Date{2023, 11, 10}, Payments: []*Payment{newPayment(500, 8400, "100")},
+			info: ReceiptInfo{WrittenOff: Date{2024, 3, 15}}},
+	}
+	prior.Validate()
+
+	current := &Eur{file: "2024.eux", Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}, accountInfo: accounts, accrual: true}
+	current.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 3, 1}, Payments: []*Payment{newPayment(500, 8400, "300")}},
+	}
+	current.Validate()
+
+	jes := []*Eur{prior, current}
+	period := resolvePeriod(Month{month: 3}, "3", jes)
+	if period != (Month{2024, 3}) {
+		t.Fatalf("resolvePeriod() = %v, want 2024-03", period)
+	}
+
+	merged, _ := mergedVatData(jes, period)
+	if got := merged[VatKey{500, Regular}]; got.NetAmount != 200_00 || got.Tax != 38_00 {
+		t.Errorf("VatData = %s / %s, want 200.00 EUR / 38.00 EUR", got.NetAmount, got.Tax)
+	}
+
+	want := []BadDebt{{7, Date{2023, 11, 10}, Date{2024, 3, 15}, 100_00}}
+	if got := prior.badDebts(period); !slices.Equal(got, want) {
+		t.Errorf("badDebts() = %v, want %v", got, want)
+	}
+}
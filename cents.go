@@ -1,8 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
-	"math"
+	"math/bits"
 	"strconv"
 	"strings"
 )
@@ -28,6 +29,13 @@ func (c Cents) FullEuros() Cents {
 	return c - c.Cents()
 }
 
+func (c Cents) abs() Cents {
+	if c < 0 {
+		return -c
+	}
+	return c
+}
+
 func (c Cents) String() string {
 	return c.Format("%d.%02d EUR")
 }
@@ -37,25 +45,76 @@ func (c Cents) EuroString() string {
 	return strconv.FormatInt(int64(c)/100, 10)
 }
 
-// NetAmount returns the net amount under the given percentage.
-// The percentage is specified in percentage notation (i.e. 20 for 20%).
-func (c Cents) NetAmount(perc int) Cents {
-	if perc == 0 {
+// Rounding defines how an exact intermediate result is rounded to full cents.
+type Rounding uint8
+
+const (
+	HalfUp   Rounding = iota // ties are rounded away from zero (kaufmännisches Runden)
+	HalfEven                 // ties are rounded to the even neighbour (Bankers' rounding)
+)
+
+// mulDiv computes c * num / den exactly and rounds the result according to the rounding mode.
+// num must not be negative and den must be positive. The product is computed in 128 bits,
+// so it only panics if the result does not fit into 64 bits.
+func (m Rounding) mulDiv(c Cents, num, den int64) Cents {
+	neg := c < 0
+	abs := uint64(c)
+	if neg {
+		abs = -abs
+	}
+
+	hi, lo := bits.Mul64(abs, uint64(num))
+	q, r := bits.Div64(hi, lo, uint64(den))
+
+	if rest := uint64(den) - r; r > rest || (r == rest && (m == HalfUp || q%2 == 1)) {
+		q++
+	}
+
+	if neg {
+		return -Cents(q)
+	}
+	return Cents(q)
+}
+
+// NetAmount returns the net amount of a gross amount under the given rate, rounded half up.
+func (c Cents) NetAmount(r Rate) Cents {
+	return c.NetAmountRounded(r, HalfUp)
+}
+
+// NetAmountRounded returns the net amount of a gross amount under the given rate, using the given rounding mode.
+func (c Cents) NetAmountRounded(r Rate, m Rounding) Cents {
+	if r == 0 {
 		return c
 	}
+	return m.mulDiv(c, int64(hundredPercent), int64(hundredPercent+r))
+}
 
-	factor := float64(perc)/100 + 1
-	return Cents(math.Round(float64(c) / factor))
+// Percentage returns the given rate of the amount, rounded half up.
+func (c Cents) Percentage(r Rate) Cents {
+	return c.PercentageRounded(r, HalfUp)
 }
 
-// Percentage returns the given percentage, where perc is specified in percentage notation (i.e. 20 for 20%).
-func (c Cents) Percentage(perc int) Cents {
-	if perc == 0 {
+// PercentageRounded returns the given rate of the amount, using the given rounding mode.
+func (c Cents) PercentageRounded(r Rate, m Rounding) Cents {
+	if r == 0 {
 		return 0
 	}
+	return m.mulDiv(c, int64(r), int64(hundredPercent))
+}
 
-	factor := float64(perc) / 100
-	return Cents(math.Round(float64(c) * factor))
+// MarshalText implements encoding.TextMarshaler, to store amounts as "123.45".
+func (c Cents) MarshalText() ([]byte, error) {
+	return []byte(c.Format("%d.%02d")), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting all formats of ParseCents.
+func (c *Cents) UnmarshalText(text []byte) error {
+	cents, err := ParseCents(string(text))
+	if err != nil {
+		return err
+	}
+	*c = cents
+	return nil
 }
 
 func parseCents(eurStr, centsStr string) (Cents, error) {
@@ -90,3 +149,59 @@ func ParseCents(str string) (Cents, error) {
 	}
 	return 0, fmt.Errorf("invalid format: '%s'", str)
 }
+
+// Rate is a tax rate in hundredths of a percent, allowing for fractional rates like 5.5%.
+type Rate int64
+
+const (
+	pct            Rate = 100 // one percent, i.e. 19 * pct is 19%
+	hundredPercent      = 100 * pct
+)
+
+func (r Rate) Format(fmtStr string) string {
+	return fmt.Sprintf(fmtStr, int64(r)/100, int64(r)%100)
+}
+
+// String formats the rate in percentage notation without trailing zeros, i.e. "19" or "5.5".
+func (r Rate) String() string {
+	if r%pct == 0 {
+		return strconv.FormatInt(int64(r/pct), 10)
+	}
+	return strings.TrimRight(r.Format("%d.%02d"), "0")
+}
+
+// ParseRate parses a rate in percentage notation with up to two decimals, i.e. "19", "5.5" or "5,50".
+func ParseRate(str string) (Rate, error) {
+	str = strings.TrimSpace(str)
+	fullStr, fracStr, _ := strings.Cut(strings.Replace(str, ",", ".", 1), ".")
+
+	full, err := strconv.ParseUint(fullStr, 10, 16)
+	if err != nil || len(fracStr) > 2 {
+		return 0, fmt.Errorf("invalid rate: '%s'", str)
+	}
+
+	var frac uint64
+	if fracStr != "" {
+		fracStr += strings.Repeat("0", 2-len(fracStr))
+		if frac, err = strconv.ParseUint(fracStr, 10, 8); err != nil {
+			return 0, fmt.Errorf("invalid rate: '%s'", str)
+		}
+	}
+
+	return Rate(full)*pct + Rate(frac), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, as used for the rates in the JES XML.
+func (r *Rate) UnmarshalText(text []byte) error {
+	rate, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// UnmarshalJSON accepts rates in the config both as number and as string.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	return r.UnmarshalText(bytes.Trim(data, `"`))
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"math/big"
 	"testing"
+	"testing/quick"
 )
 
 func TestParseCents(t *testing.T) {
@@ -104,40 +108,184 @@ func TestFullEuros(t *testing.T) {
 
 func TestNetAmount(t *testing.T) {
 	tests := []struct {
-		input Cents
-		perc  int
-		want  Cents
+		input    Cents
+		rate     Rate
+		halfUp   Cents
+		halfEven Cents
 	}{
-		{119, 19, 100},
-		{120, 20, 100},
-		{100, 0, 100},
-		{-119, 19, -100},
+		{119, 19 * pct, 100, 100},
+		{120, 20 * pct, 100, 100},
+		{107, 7 * pct, 100, 100},
+		{1055, 550, 1000, 1000},
+		{100, 0, 100, 100},
+		{-119, 19 * pct, -100, -100},
+		{3, 20 * pct, 3, 2}, // 2.5
+		{9, 20 * pct, 8, 8}, // 7.5
+		{-3, 20 * pct, -3, -2},
+		{1 << 62, 19 * pct, 3875366402039821768, 3875366402039821768},
 	}
 
 	for _, tt := range tests {
-		got := tt.input.NetAmount(tt.perc)
-		if got != tt.want {
-			t.Errorf("Cents(%d).NetAmount(%d) = %v, want %v", tt.input, tt.perc, got, tt.want)
+		if got := tt.input.NetAmount(tt.rate); got != tt.halfUp {
+			t.Errorf("Cents(%d).NetAmount(%s) = %d, want %d", tt.input, tt.rate, got, tt.halfUp)
+		}
+		if got := tt.input.NetAmountRounded(tt.rate, HalfEven); got != tt.halfEven {
+			t.Errorf("Cents(%d).NetAmountRounded(%s, HalfEven) = %d, want %d", tt.input, tt.rate, got, tt.halfEven)
 		}
 	}
 }
 
 func TestPercentage(t *testing.T) {
 	tests := []struct {
-		input Cents
-		perc  int
-		want  Cents
+		input    Cents
+		rate     Rate
+		halfUp   Cents
+		halfEven Cents
 	}{
-		{100, 19, 19},
-		{100, 20, 20},
-		{100, 0, 0},
-		{-100, 19, -19},
+		{100, 19 * pct, 19, 19},
+		{100, 20 * pct, 20, 20},
+		{100, 0, 0, 0},
+		{-100, 19 * pct, -19, -19},
+		{250, 19 * pct, 48, 48},    // 47.5
+		{150, 19 * pct, 29, 28},    // 28.5
+		{-150, 19 * pct, -29, -28}, // -28.5
+		{50, 550, 3, 3},            // 2.75
+		{1000, 550, 55, 55},
+		{1 << 62, 19 * pct, 876220343501203702, 876220343501203702},
 	}
 
 	for _, tt := range tests {
-		got := tt.input.Percentage(tt.perc)
-		if got != tt.want {
-			t.Errorf("Cents(%d).Percentage(%d) = %v, want %v", tt.input, tt.perc, got, tt.want)
+		if got := tt.input.Percentage(tt.rate); got != tt.halfUp {
+			t.Errorf("Cents(%d).Percentage(%s) = %d, want %d", tt.input, tt.rate, got, tt.halfUp)
+		}
+		if got := tt.input.PercentageRounded(tt.rate, HalfEven); got != tt.halfEven {
+			t.Errorf("Cents(%d).PercentageRounded(%s, HalfEven) = %d, want %d", tt.input, tt.rate, got, tt.halfEven)
 		}
 	}
 }
+
+// refMulDiv is the reference implementation of Rounding.mulDiv based on math/big.
+func refMulDiv(c Cents, num, den int64, m Rounding) Cents {
+	prod := new(big.Int).Mul(big.NewInt(int64(c)), big.NewInt(num))
+	q, r := new(big.Int).QuoRem(prod, big.NewInt(den), new(big.Int))
+
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	switch cmp := twice.Cmp(big.NewInt(den)); {
+	case cmp > 0, cmp == 0 && (m == HalfUp || q.Bit(0) == 1):
+		q.Add(q, big.NewInt(int64(prod.Sign())))
+	}
+	return Cents(q.Int64())
+}
+
+func TestRoundingProperties(t *testing.T) {
+	// amounts up to a trillion euros, rates up to 100%
+	amount := func(c int64) Cents { return Cents(c % 1e14) }
+	rate := func(r uint16) Rate { return Rate(r % 10001) }
+
+	for _, m := range []Rounding{HalfUp, HalfEven} {
+		percentage := func(c int64, r uint16) bool {
+			return amount(c).PercentageRounded(rate(r), m) == refMulDiv(amount(c), int64(rate(r)), int64(hundredPercent), m)
+		}
+		if err := quick.Check(percentage, nil); err != nil {
+			t.Errorf("Percentage with rounding %d: %v", m, err)
+		}
+
+		netAmount := func(c int64, r uint16) bool {
+			return amount(c).NetAmountRounded(rate(r), m) ==
+				refMulDiv(amount(c), int64(hundredPercent), int64(hundredPercent+rate(r)), m)
+		}
+		if err := quick.Check(netAmount, nil); err != nil {
+			t.Errorf("NetAmount with rounding %d: %v", m, err)
+		}
+
+		// the rounded net amount deviates by at most half a cent from the exact value
+		// and adding the tax again gives back the gross amount up to rounding
+		roundTrip := func(c int64, r uint16) bool {
+			gross := amount(c)
+			net := gross.NetAmountRounded(rate(r), m)
+			diff := net + net.PercentageRounded(rate(r), m) - gross
+			return diff >= -1 && diff <= 1
+		}
+		if err := quick.Check(roundTrip, nil); err != nil {
+			t.Errorf("round trip with rounding %d: %v", m, err)
+		}
+
+		symmetric := func(c int64, r uint16) bool {
+			return amount(c).PercentageRounded(rate(r), m) == -(-amount(c)).PercentageRounded(rate(r), m) &&
+				amount(c).NetAmountRounded(rate(r), m) == -(-amount(c)).NetAmountRounded(rate(r), m)
+		}
+		if err := quick.Check(symmetric, nil); err != nil {
+			t.Errorf("symmetry with rounding %d: %v", m, err)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rate
+		wantErr bool
+	}{
+		{"19", 1900, false},
+		{" 7 ", 700, false},
+		{"5.5", 550, false},
+		{"5,5", 550, false},
+		{"5.50", 550, false},
+		{"10.25", 1025, false},
+		{"0", 0, false},
+		{"5.505", 0, true},
+		{"-19", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+		{"19.", 1900, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseRate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRateString(t *testing.T) {
+	tests := []struct {
+		input Rate
+		want  string
+	}{
+		{1900, "19"},
+		{550, "5.5"},
+		{1025, "10.25"},
+		{5, "0.05"},
+		{0, "0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.input.String(); got != tt.want {
+			t.Errorf("Rate(%d).String() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRateUnmarshal(t *testing.T) {
+	var rates struct {
+		Number Rate `json:"number"`
+		String Rate `json:"string"`
+		XML    Rate `xml:"percent"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"number": 5.5, "string": "19"}`), &rates); err != nil {
+		t.Fatalf("Unmarshal JSON: %v", err)
+	}
+	if err := xml.Unmarshal([]byte(`<account><percent>7</percent></account>`), &rates); err != nil {
+		t.Fatalf("Unmarshal XML: %v", err)
+	}
+
+	if rates.Number != 550 || rates.String != 1900 || rates.XML != 700 {
+		t.Errorf("unmarshalled rates = %+v, want {550 1900 700}", rates)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+)
+
+// Correction is a credit note or cancellation, i.e. a receipt with a negative value
+// or one linked to the receipt it corrects via the sidecar file.
+type Correction struct {
+	Receipt  int
+	Date     Date
+	Amount   Cents
+	Corrects int   // number of the original receipt, 0 if unknown
+	Original *Date // date of the original receipt, if it is part of the JES file
+}
+
+// value returns the total value of the receipt, ignoring tax bookings.
+func (e *Eur) value(r *Receipt) Cents {
+	var sum Cents
+	for _, p := range r.Payments {
+		if _, ok := e.taxBookingAccounts[p.Account]; !ok {
+			sum += p.getValue()
+		}
+	}
+	return sum
+}
+
+// corrections returns all credit notes and cancellations in the given period.
+// They are reported in the period of the correction, not in the one of the original receipt.
+func (e *Eur) corrections(period Period) []Correction {
+	var result []Correction
+
+	for _, r := range e.Receipts {
+		if !e.booked(r) || !period.includes(r.Date) || e.carriedOver(r) {
+			continue
+		}
+
+		amount := e.value(r)
+		if amount >= 0 && r.info.Corrects == 0 {
+			continue
+		}
+
+		c := Correction{Receipt: r.Number, Date: r.Date, Amount: amount, Corrects: r.info.Corrects}
+		if c.Corrects != 0 {
+			if i := slices.IndexFunc(e.Receipts, func(o *Receipt) bool { return o.Number == c.Corrects }); i >= 0 {
+				c.Original = &e.Receipts[i].Date
+			}
+		}
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// WriteCorrections lists the credit notes and cancellations of the period.
+func WriteCorrections(w io.Writer, corrections []Correction) {
+	for _, c := range corrections {
+		fmt.Fprintf(w, "    Correction #%d (%s): %s", c.Receipt, c.Date, c.Amount)
+		switch {
+		case c.Original != nil:
+			fmt.Fprintf(w, ", corrects #%d (%s)", c.Corrects, c.Original)
+		case c.Corrects != 0:
+			fmt.Fprintf(w, ", corrects #%d (not in this file)", c.Corrects)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// negativeBases returns the base Kennzahlen with a negative amount, in ascending order.
+func negativeBases(k Kennzahlen) []int {
+	var result []int
+	for _, id := range slices.Sorted(maps.Keys(k)) {
+		switch k[id].typ {
+		case Amount, AmountOnly, Base:
+			if k[id].amount < 0 {
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
+
+// warnNegativeBases warns about negative base Kennzahlen. ELSTER accepts them, but they usually mean that
+// credit notes or cancellations exceed the turnover of the period, which should be double-checked.
+func warnNegativeBases(k Kennzahlen) {
+	for _, id := range negativeBases(k) {
+		log.Printf("WARNING: Kz %02d is negative (%s). Please check the credit notes and cancellations of the period.",
+			id, k[id].amount)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCorrections(t *testing.T) {
+	e := &Eur{
+		Start:              Date{2024, 1, 1},
+		accountInfo:        map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+		taxBookingAccounts: map[int]struct{}{1780: {}},
+	}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "119")}},
+		// credit note for #1
+		{Number: 2, Date: Date{2024, 2, 5}, Paid: true, info: ReceiptInfo{Corrects: 1},
+			Payments: []*Payment{newPayment(500, 8400, "-119")}},
+		// cancellation of an invoice from last year
+		{Number: 3, Date: Date{2024, 2, 20}, Paid: true, info: ReceiptInfo{Corrects: 99},
+			Payments: []*Payment{newPayment(500, 8400, "-238")}},
+		// unlinked negative receipt
+		{Number: 4, Date: Date{2024, 2, 25}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "-10")}},
+		// tax refund is not a correction
+		{Number: 5, Date: Date{2024, 2, 26}, Paid: true, Payments: []*Payment{newPayment(0, 1780, "-50")}},
+	}
+	e.Validate()
+
+	got := e.corrections(Month{2024, 2})
+	want := []Correction{
+		{2, Date{2024, 2, 5}, -11900, 1, &e.Receipts[0].Date},
+		{3, Date{2024, 2, 20}, -23800, 99, nil},
+		{4, Date{2024, 2, 25}, -1000, 0, nil},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("corrections() = %v, want %v", got, want)
+	}
+
+	if got := e.corrections(Month{2024, 1}); len(got) != 0 {
+		t.Errorf("corrections() of original period = %v, want none", got)
+	}
+
+	var sb strings.Builder
+	WriteCorrections(&sb, got)
+	wantOut := "    Correction #2 (2024-02-05): -119.00 EUR, corrects #1 (2024-01-10)\n" +
+		"    Correction #3 (2024-02-20): -238.00 EUR, corrects #99 (not in this file)\n" +
+		"    Correction #4 (2024-02-25): -10.00 EUR\n"
+	if out := sb.String(); out != wantOut {
+		t.Errorf("WriteCorrections() = %q, want %q", out, wantOut)
+	}
+}
+
+func TestNegativeBases(t *testing.T) {
+	k := Kennzahlen{
+		81: {amount: -10000, typ: Amount},
+		86: {amount: 10000, typ: Amount},
+		35: {amount: -500, typ: Base},
+		36: {amount: -80, typ: Tax},
+		41: {amount: -100, typ: AmountOnly},
+	}
+
+	if got, want := negativeBases(k), []int{35, 41, 81}; !slices.Equal(got, want) {
+		t.Errorf("negativeBases() = %v, want %v", got, want)
+	}
+}
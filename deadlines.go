@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status of a deadline.
+const (
+	deadlineOpen    = "offen"
+	deadlineOverdue = "überfällig"
+	deadlineFiled   = "abgegeben"
+	deadlinePaid    = "bezahlt"
+)
+
+// Deadline is the due date of a single UStVA or of the Dauerfristverlängerung.
+type Deadline struct {
+	Label  string
+	Due    time.Time
+	Status string
+}
+
+// dueDate returns the due date of the UStVA for the period: the 10th of the following month,
+// one month later with Dauerfristverlängerung, and moved to the next working day.
+func dueDate(period Period, dfv bool) time.Time {
+	end := periodEnd(period)
+	months := 1
+	if dfv {
+		months = 2
+	}
+
+	due := time.Date(end.Year, time.Month(end.Month+months), 10, 0, 0, 0, 0, time.UTC)
+	return nextWorkingDay(due)
+}
+
+// deadlines returns the deadlines of the year. `filed` holds the periods with a filed UStVA,
+// `paid` the ones with a matching tax payment, both by label.
+func deadlines(conf *Config, year Year, filed, paid map[string]bool, today time.Time) []Deadline {
+	var result []Deadline
+
+	status := func(label string, due time.Time) string {
+		switch {
+		case paid[label]:
+			return deadlinePaid
+		case filed[label]:
+			return deadlineFiled
+		case due.Before(today):
+			return deadlineOverdue
+		default:
+			return deadlineOpen
+		}
+	}
+
+	periods := periodsOfYear(year, conf.Filing)
+
+	if conf.Dfv {
+		// application for Dauerfristverlängerung incl. Sondervorauszahlung, due with the first UStVA without extension
+		label := dfvLabel(year)
+		due := dueDate(periods[0], false)
+		result = append(result, Deadline{label, due, status(label, due)})
+	}
+
+	for _, period := range periods {
+		label := periodLabel(period)
+		due := dueDate(period, conf.Dfv)
+		result = append(result, Deadline{label, due, status(label, due)})
+	}
+
+	return result
+}
+
+// weekdays are the German abbreviations of the days of the week, starting with Sunday like `time.Weekday`.
+var weekdays = [...]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"}
+
+// WriteDeadlines prints the deadlines as a table.
+func WriteDeadlines(w io.Writer, deadlines []Deadline) {
+	for _, d := range deadlines {
+		fmt.Fprintf(w, "%s\t%s %s\t%s\n", d.Label, weekdays[d.Due.Weekday()], d.Due.Format(dateLayout), d.Status)
+	}
+}
+
+// WriteICS writes the deadlines as all-day events in the iCalendar format.
+func WriteICS(w io.Writer, deadlines []Deadline, stamp time.Time) {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//jesva//Fristen//DE",
+	}
+
+	for _, d := range deadlines {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+strings.ReplaceAll(d.Label, " ", "-")+"@jesva",
+			"DTSTAMP:"+stamp.UTC().Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+d.Due.Format("20060102"),
+			"DTEND;VALUE=DATE:"+d.Due.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:UStVA "+d.Label,
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			log.Fatalf("Writing calendar: %v", err)
+		}
+	}
+}
+
+// cmdDeadlines handles
+//
+//	deadlines [-ics <file>] <year> [<xml-file>... | <jes.file>...]
+//
+// The XML files may be UStVAs as well as the Dauerfristverlängerung of the year.
+func cmdDeadlines(conf *Config, args []string) {
+	var icsFile string
+	if len(args) >= 2 && args[0] == "-ics" {
+		icsFile = args[1]
+		args = args[2:]
+	}
+
+	if len(args) < 1 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	year := yearArg(args[0])
+	filed := make(map[string]bool)
+	paid := make(map[string]bool)
+
+	var jesFiles []string
+	for _, file := range args[1:] {
+		if strings.EqualFold(filepath.Ext(file), jesExt) {
+			jesFiles = append(jesFiles, file)
+			continue
+		}
+
+		anmeldung := readAnmeldung(file)
+		if dfv := anmeldung.DFV; dfv != nil {
+			if dfv.Jahr == int(year) {
+				filed[dfvLabel(year)] = true
+			}
+			continue
+		}
+
+		ustva := anmeldung.UStVA
+		if ustva == nil {
+			log.Fatalf("XML file '%s' is neither an UStVA nor a Dauerfristverlängerung.", file)
+		}
+		if ustva.Jahr != int(year) {
+			continue
+		}
+		for _, period := range periodsOfYear(year, conf.Filing) {
+			if period.String() == ustva.Zeitraum {
+				filed[periodLabel(period)] = true
+			}
+		}
+	}
+
+	if len(jesFiles) > 0 {
+		jes := loadJes(conf, jesFiles)
+
+		entries := reconcileEntries(conf, jes, year)
+		reconcile(entries, allTaxBookings(jes))
+		for _, e := range entries {
+			if e.Booking != nil {
+				paid[e.Label] = true
+			}
+		}
+	}
+
+	t := now()
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	result := deadlines(conf, year, filed, paid, today)
+	WriteDeadlines(os.Stdout, result)
+
+	if icsFile != "" {
+		f, err := os.Create(icsFile)
+		if err != nil {
+			log.Fatalf("Creating calendar '%s': %v", icsFile, err)
+		}
+		defer f.Close()
+
+		WriteICS(f, result, creationTime())
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEaster(t *testing.T) {
+	tests := []struct {
+		year int
+		want string
+	}{
+		{2024, "2024-03-31"},
+		{2025, "2025-04-20"},
+		{2026, "2026-04-05"},
+	}
+
+	for _, tt := range tests {
+		if got := easter(tt.year).Format(dateLayout); got != tt.want {
+			t.Errorf("easter(%d) = %s, want %s", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	tests := []struct {
+		period Period
+		dfv    bool
+		want   string
+	}{
+		{Month{2024, 1}, false, "2024-02-12"},  // Saturday -> Monday
+		{Month{2024, 3}, false, "2024-04-10"},  // regular
+		{Month{2024, 3}, true, "2024-05-10"},   // extension
+		{Month{2024, 4}, true, "2024-06-10"},   // regular with extension
+		{Month{2025, 12}, false, "2026-01-12"}, // next year, Saturday -> Monday
+		{Month{2025, 4}, false, "2025-05-12"},  // Saturday -> Monday
+		{Month{2026, 4}, true, "2026-06-10"},   // regular
+		{Month{2027, 3}, true, "2027-05-10"},   // regular
+		{Month{2029, 3}, true, "2029-05-11"},   // Christi Himmelfahrt -> Friday
+		{Quarter{2024, 4}, false, "2025-01-10"},
+		{Quarter{2024, 4}, true, "2025-02-10"},
+		{Quarter{2026, 3}, false, "2026-10-12"}, // Saturday -> Monday
+	}
+
+	for _, tt := range tests {
+		if got := dueDate(tt.period, tt.dfv).Format(dateLayout); got != tt.want {
+			t.Errorf("dueDate(%s, %v) = %s, want %s", periodLabel(tt.period), tt.dfv, got, tt.want)
+		}
+	}
+}
+
+func TestNextWorkingDay(t *testing.T) {
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{"2024-05-09", "2024-05-10"}, // Christi Himmelfahrt
+		{"2024-03-29", "2024-04-02"}, // Karfreitag, weekend, Ostermontag
+		{"2024-12-25", "2024-12-27"}, // Weihnachten
+		{"2025-06-09", "2025-06-10"}, // Pfingstmontag
+		{"2025-10-03", "2025-10-06"}, // Tag der Deutschen Einheit, weekend
+		{"2025-10-02", "2025-10-02"}, // regular
+	}
+
+	for _, tt := range tests {
+		day, _ := time.Parse(dateLayout, tt.day)
+		if got := nextWorkingDay(day).Format(dateLayout); got != tt.want {
+			t.Errorf("nextWorkingDay(%s) = %s, want %s", tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestDeadlines(t *testing.T) {
+	conf := &Config{Filing: filingQuarterly, Dfv: true}
+	filed := map[string]bool{"2024Q1": true, "2024Q2": true}
+	paid := map[string]bool{"2024Q1": true, dfvLabel(2024): true}
+	today := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	got := deadlines(conf, 2024, filed, paid, today)
+	want := []struct {
+		label  string
+		due    string
+		status string
+	}{
+		// due with the first quarterly UStVA
+		{"2024 DFV", "2024-04-10", deadlinePaid},
+		{"2024Q1", "2024-05-10", deadlinePaid},
+		{"2024Q2", "2024-08-12", deadlineFiled},
+		{"2024Q3", "2024-11-11", deadlineOpen},
+		{"2024Q4", "2025-02-10", deadlineOpen},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d deadlines, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Label != w.label || g.Due.Format(dateLayout) != w.due || g.Status != w.status {
+			t.Errorf("deadline %d = {%s %s %s}, want {%s %s %s}",
+				i, g.Label, g.Due.Format(dateLayout), g.Status, w.label, w.due, w.status)
+		}
+	}
+}
+
+func TestDeadlinesMonthlyDfv(t *testing.T) {
+	conf := &Config{Filing: filingMonthly, Dfv: true}
+	today := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got := deadlines(conf, 2025, nil, nil, today)
+	if d := got[0]; d.Label != "2025 DFV" || d.Due.Format(dateLayout) != "2025-02-10" || d.Status != deadlineOpen {
+		t.Errorf("DFV deadline = {%s %s %s}, want {2025 DFV 2025-02-10 %s}", d.Label, d.Due.Format(dateLayout), d.Status, deadlineOpen)
+	}
+}
+
+func TestWriteDeadlines(t *testing.T) {
+	var sb strings.Builder
+	WriteDeadlines(&sb, []Deadline{{"2024-03", time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC), deadlineOpen}})
+
+	if want := "2024-03\tMi 2024-04-10\toffen\n"; sb.String() != want {
+		t.Errorf("WriteDeadlines = %q, want %q", sb.String(), want)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	var sb strings.Builder
+	due := time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC)
+	WriteICS(&sb, []Deadline{{"2024-03", due, deadlineOpen}}, due)
+
+	out := sb.String()
+	for _, line := range []string{"BEGIN:VEVENT\r\n", "DTSTART;VALUE=DATE:20240410\r\n", "DTEND;VALUE=DATE:20240411\r\n", "SUMMARY:UStVA 2024-03\r\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("calendar misses %q:\n%s", line, out)
+		}
+	}
+}
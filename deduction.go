@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+)
+
+// DeductionRule limits the input tax deduction of payments on the given booking accounts to a share,
+// e.g. for costs split between taxable and exempt activity.
+type DeductionRule struct {
+	// Booking accounts the rule applies to
+	BookingAccounts []int `json:"bookingAccounts"`
+	// Deductible share in percent
+	Share Rate `json:"share"`
+}
+
+// prepareDeduction checks the rules for partial input tax deduction and registers the non-deductible parts.
+func (e *Eur) prepareDeduction(rules []DeductionRule) {
+	for _, r := range rules {
+		if len(r.BookingAccounts) == 0 {
+			log.Fatalf("Rule for partial input tax deduction needs booking accounts.")
+		}
+		if r.Share > hundredPercent {
+			log.Fatalf("Deductible share of %s%% exceeds 100%%.", r.Share)
+		}
+	}
+	for _, r := range e.Receipts {
+		if r.info.Deductible != nil && *r.info.Deductible > hundredPercent {
+			log.Fatalf("Deductible share of %s%% for receipt #%d exceeds 100%%.", *r.info.Deductible, r.Number)
+		}
+	}
+
+	e.deduction = rules
+}
+
+// isInputTax returns whether the tax account holds deductible input tax.
+func isInputTax(acc TaxAccount) bool {
+	return acc.IsExpense() && slices.ContainsFunc(mappings, func(m Mapping) bool {
+		return m.account == acc && m.category == Regular && m.typ == Tax
+	})
+}
+
+// deductionShare returns the deductible share of the input tax of the payment, if it is only partially deductible.
+// The share of the receipt from the sidecar file takes precedence over the rules of the config.
+func (e *Eur) deductionShare(p *Payment, acc TaxAccount) (Rate, bool) {
+	if !isInputTax(acc) {
+		return 0, false
+	}
+
+	if share := p.receipt.info.Deductible; share != nil {
+		return *share, *share != hundredPercent
+	}
+
+	for _, r := range e.deduction {
+		if slices.Contains(r.BookingAccounts, p.Account) {
+			return r.Share, r.Share != hundredPercent
+		}
+	}
+	return 0, false
+}
+
+// splitDeductible splits the input tax into the deductible and the non-deductible part.
+func splitDeductible(tax Cents, share Rate) (Cents, Cents) {
+	deductible := tax.Percentage(share)
+	return deductible, tax - deductible
+}
+
+// NonDeductibleEntry is the non-deductible input tax of one payment, to be booked as cost.
+type NonDeductibleEntry struct {
+	Receipt        int
+	Date           Date
+	Account        TaxAccount
+	BookingAccount int
+	Share          Rate
+	Tax            Cents // full input tax
+	NonDeductible  Cents
+}
+
+// nonDeductible lists the partially deductible input taxes of the period.
+func (e *Eur) nonDeductible(period Period) []NonDeductibleEntry {
+	var entries []NonDeductibleEntry
+
+	for p, acc := range e.taxPayments(period) {
+		key, percent := e.classify(p, acc)
+		share, ok := e.deductionShare(p, key.Account)
+		if !ok {
+			continue
+		}
+
+		tax := p.getTax(percent)
+		_, nonDeductible := splitDeductible(tax, share)
+		entries = append(entries, NonDeductibleEntry{p.receipt.Number, p.receipt.Date, key.Account, p.Account,
+			share, tax, nonDeductible})
+	}
+
+	return entries
+}
+
+// WriteNonDeductible lists the non-deductible input taxes and their sum.
+func WriteNonDeductible(w io.Writer, entries []NonDeductibleEntry) {
+	var sum Cents
+	for _, n := range entries {
+		fmt.Fprintf(w, "    Non-deductible #%d (%s, Kto %d/%d, %s%% deductible): %s of %s\n",
+			n.Receipt, n.Date, n.Account, n.BookingAccount, n.Share, n.NonDeductible, n.Tax)
+		sum += n.NonDeductible
+	}
+	if len(entries) > 0 {
+		fmt.Fprintf(w, "    Non-deductible input tax (to be booked as cost): %s\n", sum)
+	}
+}
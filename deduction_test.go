@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPartialDeduction(t *testing.T) {
+	half := 50 * pct
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		100: {Number: 100, Percent: 19 * pct},
+		500: {Number: 500, Percent: 19 * pct},
+	}}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(100, 4920, "100")}},
+		{Number: 2, Date: Date{2024, 1, 11}, Paid: true, Payments: []*Payment{newPayment(100, 4930, "100")}},
+		// receipt share overrides the rule
+		{Number: 3, Date: Date{2024, 1, 12}, Paid: true, Payments: []*Payment{newPayment(100, 4920, "100")},
+			info: ReceiptInfo{Deductible: &half}},
+		// income is not affected
+		{Number: 4, Date: Date{2024, 1, 13}, Paid: true, Payments: []*Payment{newPayment(500, 4920, "100")}},
+	}
+	e.prepareDeduction([]DeductionRule{{BookingAccounts: []int{4920}, Share: 30 * pct}})
+	e.Validate()
+
+	vatData := e.VatData(Month{2024, 1})
+	// 19.00 + 30% of 19.00 + 50% of 19.00
+	if got, want := vatData[VatKey{100, Regular}].Tax, Cents(1900+570+950); got != want {
+		t.Errorf("deductible input tax = %s, want %s", got, want)
+	}
+	if got, want := vatData[VatKey{100, NonDeductible}].Tax, Cents(1330+950); got != want {
+		t.Errorf("non-deductible input tax = %s, want %s", got, want)
+	}
+	if got, want := vatData[VatKey{500, Regular}].Tax, Cents(1900); got != want {
+		t.Errorf("output tax = %s, want %s", got, want)
+	}
+
+	k := kennzahlenFromVatData(vatData)
+	if got := k[66].amountString(); got != "34.20" {
+		t.Errorf("Kz 66 = %s, want 34.20", got)
+	}
+
+	var sb strings.Builder
+	WriteNonDeductible(&sb, e.nonDeductible(Month{2024, 1}))
+	want := "    Non-deductible #1 (2024-01-10, Kto 100/4920, 30% deductible): 13.30 EUR of 19.00 EUR\n" +
+		"    Non-deductible #3 (2024-01-12, Kto 100/4920, 50% deductible): 9.50 EUR of 19.00 EUR\n" +
+		"    Non-deductible input tax (to be booked as cost): 22.80 EUR\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WriteNonDeductible() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+)
+
+// svzStoreName is the file the computed Sondervorauszahlungen are stored in, by year.
+const svzStoreName = "sondervorauszahlung.json"
+
+const (
+	// Sondervorauszahlung in the Dauerfristverlängerung
+	KzDfvSvz = 38
+)
+
+// DFV holds the content of the application for Dauerfristverlängerung (USt 1 H).
+type DFV struct {
+	Jahr         int    `xml:"Jahr"`
+	Steuernummer string `xml:"Steuernummer"`
+	WIdNr        string `xml:"WIdNr,omitempty"`
+	Kz38         string `xml:"Kz38"`
+}
+
+// dfvLabel names the Dauerfristverlängerung of the year, and the Sondervorauszahlung paid with it.
+func dfvLabel(year Year) string {
+	return fmt.Sprintf("%d DFV", year)
+}
+
+// computeSvz returns the Sondervorauszahlung, which is 1/11 of the sum of last year's prepayments.
+func computeSvz(lastYearTax Cents) Cents {
+	if lastYearTax <= 0 {
+		return 0
+	}
+	// rounded half up
+	return (lastYearTax*2 + 11) / 22
+}
+
+// lastYearTaxFromXml sums up the tax of the filed UStVAs. A Sondervorauszahlung therein (Kz 39) is ignored.
+func lastYearTaxFromXml(xmls []string) Cents {
+	var sum Cents
+	for _, xmlFile := range xmls {
+		kennzahlen := readUStVAXml(xmlFile)
+		tax := kennzahlen.TaxSum(taxPerTotal) // filed Kennzahlen lack the taxes of the single receipts
+		debug("%s:\t%s", xmlFile, tax)
+		sum += tax
+	}
+	return sum
+}
+
+// readSvzStore reads the stored Sondervorauszahlungen. A missing store is empty.
+func readSvzStore() map[int]Cents {
+	store := make(map[int]Cents)
+
+	data, err := os.ReadFile(svzStoreName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store
+	}
+	if err != nil {
+		log.Fatalf("Reading '%s': %v", svzStoreName, err)
+	}
+
+	if err = json.Unmarshal(data, &store); err != nil {
+		log.Fatalf("Parsing '%s': %v", svzStoreName, err)
+	}
+	return store
+}
+
+// storeSvz stores the Sondervorauszahlung of the given year, keeping the other years.
+func storeSvz(year int, svz Cents) {
+	store := readSvzStore()
+	store[year] = svz
+
+	data, err := json.MarshalIndent(store, "", "    ")
+	if err != nil {
+		log.Fatalf("Encoding '%s': %v", svzStoreName, err)
+	}
+
+	if err = os.WriteFile(svzStoreName, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("Writing '%s': %v", svzStoreName, err)
+	}
+}
+
+// svzForPeriod returns the Sondervorauszahlung to deduct in the given period, which is only done in the final
+// period of the year. An amount explicitly `given` takes precedence over the configured and the stored ones.
+func svzForPeriod(conf *Config, period Period, periodStr string, given Cents) Cents {
+	final := conf.isFinalPeriod(period)
+
+	if given != 0 {
+		if !final {
+			log.Fatalf("The Sondervorauszahlung is only deducted in the final period of the year "+
+				"(December for monthly, Q4 for quarterly filers), not in '%s'.", periodStr)
+		}
+		return given
+	}
+
+	if !final {
+		return 0
+	}
+
+	svz, source := knownSvz(conf, int(period.Year()))
+	if svz != 0 {
+		log.Printf("Applying %s Sondervorauszahlung of %s.", source, svz)
+	}
+	return svz
+}
+
+// knownSvz returns the Sondervorauszahlung of the year from the config or, if not configured there, from the store.
+// The second return value names the source.
+func knownSvz(conf *Config, year int) (Cents, string) {
+	if svz, ok := conf.Svz[year]; ok {
+		return svz, "configured"
+	}
+	return readSvzStore()[year], "stored"
+}
+
+// lastYearTaxFromJes computes the tax of the year from the JES files, as the sum of the Zahllast of its UStVAs.
+// Of the Vorsteuerberichtigung, only the corrections made in the UStVAs are included.
+func lastYearTaxFromJes(conf *Config, jes []*Eur, year Year) Cents {
+	var tax Cents
+	for _, period := range periodsOfYear(year, conf.Filing) {
+		tax += periodKennzahlen(conf, jes, period).TaxSum(conf.TaxComputation)
+	}
+	return tax
+}
+
+// WriteDfvFile writes the XML for the Dauerfristverlängerung of the given year to the Writer.
+func WriteDfvFile(w io.Writer, conf *Config, jesData *Eur, year int, svz Cents) {
+	a := anmeldungForYear(kindDFV, year)
+	a.Datenlieferant = fillDatenlieferant(conf, jesData)
+	a.Unternehmer = fillUnternehmer(conf, jesData)
+	a.DFV = &DFV{
+		Jahr:         year,
+		Steuernummer: conf.UStNr,
+		WIdNr:        conf.WIdNr,
+		Kz38:         svz.Format("%d.%02d"),
+	}
+
+	writeAnmeldung(w, a)
+}
+
+// cmdDFV handles both
+//
+//	dfv <jes.file> [<jes.file>...] <year>
+//	dfv <year> <xml-file 1, ..., xml-file n>
+//
+// where the JES files or the UStVA XML files are from the year before <year>.
+func cmdDFV(conf *Config, args []string) {
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	var year int
+	var lastYearTax Cents
+	jesData := new(Eur)
+
+	if y, err := strconv.Atoi(args[0]); err == nil && len(args[0]) == 4 {
+		year = y
+		lastYearTax = lastYearTaxFromXml(args[1:])
+	} else {
+		jesFiles, args := splitJesArgs(args)
+		year = int(yearArg(args[0]))
+
+		jes := loadJes(conf, jesFiles)
+		resolvePeriod(Year(year-1), strconv.Itoa(year-1), jes)
+		lastYearTax = lastYearTaxFromJes(conf, jes, Year(year-1))
+		jesData = jes[0]
+	}
+
+	svz := computeSvz(lastYearTax)
+	WriteDfvFile(os.Stdout, conf, jesData, year, svz)
+	storeSvz(year, svz)
+
+	fmt.Fprintf(os.Stderr, "*** Tax %d: %s => Sondervorauszahlung %d: %s ***\n", year-1, lastYearTax, year, svz)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestComputeSvz(t *testing.T) {
+	tests := []struct {
+		input Cents
+		want  Cents
+	}{
+		{110000, 10000},
+		{11, 1},
+		{5, 0},
+		{6, 1},
+		{0, 0},
+		{-110000, 0},
+	}
+
+	for _, tt := range tests {
+		if got := computeSvz(tt.input); got != tt.want {
+			t.Errorf("computeSvz(%s) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLastYearTaxFromJes(t *testing.T) {
+	e := &Eur{
+		Start:       Date{2024, 1, 1},
+		End:         Date{2024, 12, 31},
+		accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+	}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 3, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100000")}},
+	}
+	e.Validate()
+
+	conf := &Config{
+		Filing: filingQuarterly,
+		InputTaxCorrections: []InputTaxCorrection{
+			// -6500 EUR, made in the final UStVA
+			{InputTax: 65000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 50 * pct}},
+			// -570 EUR, only part of the annual return
+			{InputTax: 9500_00, From: Date{2023, 3, 10}, Usage: map[int]Rate{2023: 80 * pct, 2024: 50 * pct}},
+		},
+	}
+
+	// 19000 EUR tax plus the paid back input tax
+	if got, want := lastYearTaxFromJes(conf, []*Eur{e}, 2024), Cents(25500_00); got != want {
+		t.Errorf("lastYearTaxFromJes() = %s, want %s", got, want)
+	}
+}
+
+func TestLastYearTaxFromJesPerPeriod(t *testing.T) {
+	e := &Eur{
+		Start:       Date{2024, 1, 1},
+		End:         Date{2024, 12, 31},
+		accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+	}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100.60")}},
+		{Number: 2, Date: Date{2024, 2, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100.60")}},
+	}
+	e.Validate()
+
+	// each UStVA reports Kz 81 in full euros, so the year total of 201 EUR would be too much
+	if got, want := lastYearTaxFromJes(&Config{}, []*Eur{e}, 2024), Cents(38_00); got != want {
+		t.Errorf("lastYearTaxFromJes() = %s, want %s", got, want)
+	}
+}
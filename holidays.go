@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+)
+
+// easter returns Easter Sunday of the given year (Gregorian calendar, anonymous algorithm).
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// isHoliday returns whether the day is a nationwide public holiday in Germany.
+func isHoliday(t time.Time) bool {
+	switch t.Month() {
+	case time.January:
+		if t.Day() == 1 { // Neujahr
+			return true
+		}
+	case time.May:
+		if t.Day() == 1 { // Tag der Arbeit
+			return true
+		}
+	case time.October:
+		if t.Day() == 3 { // Tag der Deutschen Einheit
+			return true
+		}
+	case time.December:
+		if t.Day() == 25 || t.Day() == 26 { // Weihnachten
+			return true
+		}
+	}
+
+	sunday := easter(t.Year())
+	switch t.Sub(sunday) / (24 * time.Hour) {
+	case -2, // Karfreitag
+		1,  // Ostermontag
+		39, // Christi Himmelfahrt
+		50: // Pfingstmontag
+		return true
+	}
+
+	return false
+}
+
+// nextWorkingDay returns the day itself, if it is a working day, or the next one otherwise (§108 Abs. 3 AO).
+func nextWorkingDay(t time.Time) time.Time {
+	for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday || isHoliday(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"cmp"
+	"log"
+)
+
+// KzVstBerichtigung is the Kennzahl for the Berichtigung des Vorsteuerabzugs (§15a UStG).
+const KzVstBerichtigung = 64
+
+// Thresholds of §44 UStDV.
+const (
+	minInputTax          Cents = 1000_00 // Abs. 1: no correction for smaller input taxes
+	minCorrection        Cents = 1000_00 // Abs. 2: minimal correction for usage changes below `minUsageChange`
+	minUsageChange       Rate  = 10 * pct
+	maxAnnualCorrection  Cents = 6000_00 // Abs. 3: smaller corrections are only made in the annual return
+	defaultCorrectionYrs       = 5       // 10 for real estate
+)
+
+// InputTaxCorrection describes an asset whose usage for VAT-exempt activity changed, so its
+// input tax needs to be corrected over several years (Vorsteuerberichtigung, §15a UStG).
+type InputTaxCorrection struct {
+	// Purchase receipt in the JES file covering the start of use, to take the input tax from
+	Receipt int `json:"receipt"`
+	// Optional: total input tax of the purchase; necessary if the JES file of the purchase is not given
+	InputTax Cents `json:"inputTax"`
+	// Start of use
+	From Date `json:"from"`
+	// Optional: years of the correction period, 5 by default (10 for real estate)
+	Years int `json:"years"`
+	// Share of use with input tax deduction per year. The share of the first year is the original one,
+	// years not given are considered unchanged.
+	Usage map[int]Rate `json:"usage"`
+	// Optional: date of the change of use per year, to report corrections above 6000 EUR in the right UStVA period
+	Changed map[int]Date `json:"changed"`
+}
+
+// months returns the number of months of the given year within the correction period.
+// Use starting after the 15th of a month starts the correction period in the next month (§45 UStDV).
+func (c InputTaxCorrection) months(year int) int {
+	start := c.From.Year*12 + c.From.Month - 1
+	if c.From.Day > 15 {
+		start++
+	}
+	end := start + cmp.Or(c.Years, defaultCorrectionYrs)*12
+
+	first := max(start, year*12)
+	last := min(end, (year+1)*12)
+	return max(last-first, 0)
+}
+
+// amount returns the correction of the given year. Positive amounts are additional input tax,
+// negative ones are paid back. The thresholds of §44 Abs. 1 and 2 UStDV are applied.
+func (c InputTaxCorrection) amount(year int) Cents {
+	if c.InputTax <= minInputTax {
+		return 0
+	}
+
+	original, ok := c.Usage[c.From.Year]
+	if !ok {
+		log.Fatalf("Vorsteuerberichtigung for receipt #%d lacks the usage of the first year %d.", c.Receipt, c.From.Year)
+	}
+	share, ok := c.Usage[year]
+	if !ok {
+		share = original
+	}
+	change := share - original
+
+	inputTax := c.InputTax
+	if change < 0 {
+		inputTax, change = -inputTax, -change
+	}
+
+	months := int64(c.months(year))
+	amount := HalfUp.mulDiv(inputTax, int64(change)*months, int64(hundredPercent)*int64(cmp.Or(c.Years, defaultCorrectionYrs))*12)
+
+	if change < minUsageChange && amount.abs() <= minCorrection {
+		return 0
+	}
+	return amount
+}
+
+// resolve takes the input tax from the purchase receipt, if not given explicitly.
+func (c InputTaxCorrection) resolve(jes []*Eur) InputTaxCorrection {
+	if c.From.IsZero() {
+		log.Fatalf("Vorsteuerberichtigung for receipt #%d lacks the start of use (`from`).", c.Receipt)
+	}
+	if c.InputTax != 0 {
+		return c
+	}
+
+	for _, e := range jes {
+		if !e.coversYear(c.From.Year) {
+			continue
+		}
+		for _, r := range e.Receipts {
+			if r.Number == c.Receipt {
+				c.InputTax = e.inputTax(r)
+				return c
+			}
+		}
+	}
+
+	log.Fatalf("Vorsteuerberichtigung for receipt #%d: receipt not found, please give `inputTax`.", c.Receipt)
+	return c
+}
+
+// inUStVA returns whether a correction above 6000 EUR in the given year is made in the UStVA of the period:
+// the one the usage changed in (§44 Abs. 3 UStDV). Without a known date of the change, this is the final
+// period of the year.
+func (c InputTaxCorrection) inUStVA(conf *Config, year int, period Period) bool {
+	if changed, ok := c.Changed[year]; ok {
+		return period.includes(changed)
+	}
+	return conf.isFinalPeriod(period)
+}
+
+// inputTaxCorrection returns the Kz 64 of the given period, if any. For the year, this is the sum of all
+// corrections. In the UStVA, only corrections above 6000 EUR are made, see `inUStVA`.
+func inputTaxCorrection(conf *Config, jes []*Eur, period Period) (Kennzahl, bool) {
+	_, isYear := period.(Year)
+
+	year := int(period.Year())
+	var sum Cents
+	for _, c := range conf.InputTaxCorrections {
+		if !isYear && !c.inUStVA(conf, year, period) {
+			continue
+		}
+
+		c = c.resolve(jes)
+		amount := c.amount(year)
+		if amount == 0 || (!isYear && amount.abs() <= maxAnnualCorrection) {
+			continue
+		}
+
+		debug("\t=> Kz %02d (§15a, #%d):\t\t%s", KzVstBerichtigung, c.Receipt, amount)
+		sum += amount
+	}
+
+	if sum == 0 {
+		return Kennzahl{}, false
+	}
+	return Kennzahl{withFraction: true, amount: sum, typ: Tax, account: 0}, true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestInputTaxCorrectionAmount(t *testing.T) {
+	// car bought 2023-03-10 with 9500 EUR input tax, 80% used for taxable turnover in 2023
+	car := InputTaxCorrection{
+		Receipt:  7,
+		InputTax: 9500_00,
+		From:     Date{2023, 3, 10},
+		Usage:    map[int]Rate{2023: 80 * pct, 2024: 50 * pct, 2025: 85 * pct, 2026: 100 * pct},
+	}
+
+	tests := []struct {
+		c    InputTaxCorrection
+		year int
+		want Cents
+	}{
+		{car, 2023, 0},
+		// 9500 / 5 * -30%
+		{car, 2024, -570_00},
+		// change of 5 percentage points and at most 1000 EUR
+		{car, 2025, 0},
+		// 9500 / 5 * 20%
+		{car, 2026, 380_00},
+		// only January and February left in the correction period: 9500 / 60 * 2 * 20%
+		{InputTaxCorrection{InputTax: 9500_00, From: Date{2023, 3, 10}, Usage: map[int]Rate{2023: 80 * pct, 2028: pct * 100}}, 2028, 63_33},
+		// outside the correction period
+		{car, 2029, 0},
+		// purely VAT-exempt use: 2000 / 5 * -100%
+		{InputTaxCorrection{InputTax: 2000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 0}}, 2024, -400_00},
+		// year not given is unchanged
+		{InputTaxCorrection{InputTax: 2000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 0}}, 2025, 0},
+		// input tax too small
+		{InputTaxCorrection{InputTax: 1000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 0}}, 2024, 0},
+		// real estate over 10 years: 120000 / 10 * -50%
+		{InputTaxCorrection{InputTax: 120000_00, From: Date{2020, 1, 1}, Years: 10,
+			Usage: map[int]Rate{2020: 100 * pct, 2024: 50 * pct}}, 2024, -6000_00},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.amount(tt.year); got != tt.want {
+			t.Errorf("amount(%d) of %+v = %s, want %s", tt.year, tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestInputTaxCorrectionMonths(t *testing.T) {
+	tests := []struct {
+		from Date
+		year int
+		want int
+	}{
+		{Date{2023, 3, 10}, 2023, 10},
+		{Date{2023, 3, 16}, 2023, 9},
+		{Date{2023, 3, 10}, 2027, 12},
+		{Date{2023, 3, 10}, 2028, 2},
+		{Date{2023, 3, 16}, 2028, 3},
+		{Date{2023, 3, 10}, 2022, 0},
+	}
+
+	for _, tt := range tests {
+		c := InputTaxCorrection{From: tt.from}
+		if got := c.months(tt.year); got != tt.want {
+			t.Errorf("months(%d) from %s = %d, want %d", tt.year, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestInputTaxCorrectionKz(t *testing.T) {
+	conf := &Config{
+		Filing: filingMonthly,
+		InputTaxCorrections: []InputTaxCorrection{
+			// -570 EUR
+			{InputTax: 9500_00, From: Date{2023, 3, 10}, Usage: map[int]Rate{2023: 80 * pct, 2024: 50 * pct}},
+			// -6500 EUR
+			{InputTax: 65000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 50 * pct}},
+			// -7000 EUR, changed in May 2024
+			{InputTax: 70000_00, From: Date{2023, 1, 1}, Usage: map[int]Rate{2023: 100 * pct, 2024: 50 * pct},
+				Changed: map[int]Date{2024: {2024, 5, 20}}},
+		},
+	}
+
+	tests := []struct {
+		period Period
+		want   Cents
+		ok     bool
+	}{
+		{Month{2024, 11}, 0, false},
+		{Month{2024, 5}, -7000_00, true},
+		{Month{2024, 12}, -6500_00, true},
+		{Year(2024), -14070_00, true},
+		{Year(2022), 0, false},
+	}
+
+	for _, tt := range tests {
+		kz, ok := inputTaxCorrection(conf, nil, tt.period)
+		if ok != tt.ok || kz.amount != tt.want {
+			t.Errorf("inputTaxCorrection(%s) = %s, %v; want %s, %v", periodLabel(tt.period), kz.amount, ok, tt.want, tt.ok)
+		}
+	}
+
+	// a positive Kz 64 reduces the Zahllast
+	k := Kennzahlen{KzVstBerichtigung: {withFraction: true, amount: 100_00, typ: Tax}}
+	if got := k.TaxSum(taxPerTotal); got != -100_00 {
+		t.Errorf("TaxSum() = %s, want -100.00 EUR", got)
+	}
+}
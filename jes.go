@@ -3,14 +3,20 @@ package main
 import (
 	"archive/zip"
 	"cmp"
+	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"iter"
 	"log"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// jesExt is the file extension of JES files.
+const jesExt = ".eux"
+
 type Eur struct {
 	XmlName            xml.Name   `xml:"eur"`
 	Name               string     `xml:"general>name"`
@@ -23,6 +29,16 @@ type Eur struct {
 	Accounts           []Accounts `xml:"accounts"`
 	accountInfo        map[TaxAccount]Account
 	taxBookingAccounts map[int]struct{} // accounts where taxes are booked as revenue, e.g. paid taxes
+	rates              []RateChange     // deviating tax rates, see `rate`
+	taxFree            []TaxFreeRule
+	oss                []OSSRule
+	reverseCharge      []ReverseChargeRule
+	privateUse         []PrivateUseRule
+	deduction          []DeductionRule
+	refundAccounts     []int
+	accrual            bool // Sollversteuerung
+	smallBusinessUntil Date // end of the Kleinunternehmer status
+	file               string
 }
 
 type Date struct {
@@ -31,12 +47,55 @@ type Date struct {
 	Day   int `xml:"day,attr"`
 }
 
+const dateLayout = "2006-01-02"
+
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+func (d Date) compare(other Date) int {
+	return cmp.Or(
+		cmp.Compare(d.Year, other.Year),
+		cmp.Compare(d.Month, other.Month),
+		cmp.Compare(d.Day, other.Day),
+	)
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func parseDate(str string) (Date, error) {
+	t, err := time.Parse(dateLayout, str)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t.Year(), int(t.Month()), t.Day()}, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Dates are expected in the form "2006-01-02".
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	date, err := parseDate(str)
+	if err != nil {
+		return err
+	}
+
+	*d = date
+	return nil
+}
+
 type Receipt struct {
-	Number           int        `xml:"number"`
-	Date             Date       `xml:"date"`
-	Paid             bool       `xml:"paid,attr"`
-	Payments         []*Payment `xml:"payment"`
-	DepreciationDate *Date      `xml:"depreciationplan>date"`
+	Number           int         `xml:"number"`
+	Date             Date        `xml:"date"`
+	Paid             bool        `xml:"paid,attr"`
+	Payments         []*Payment  `xml:"payment"`
+	DepreciationDate *Date       `xml:"depreciationplan>date"`
+	info             ReceiptInfo // additional data from the sidecar file
 }
 
 type Payment struct {
@@ -60,7 +119,7 @@ type Accounts struct {
 type Account struct {
 	TaxAccount bool `xml:"taxaccount,attr"`
 	Number     int  `xml:"number"`
-	Percent    int  `xml:"percent"`
+	Percent    Rate `xml:"percent"`
 }
 
 type TaxAccount uint16
@@ -81,6 +140,29 @@ func (e *Eur) Year() int {
 	return e.Start.Year
 }
 
+// coversYear returns whether the business year range of the JES file touches the given calendar year.
+func (e *Eur) coversYear(year int) bool {
+	return year >= e.Start.Year && year <= e.End.Year
+}
+
+// missingMonths returns the labels of all months of the period, which are not completely part of the business year
+// of any of the JES files.
+func missingMonths(jes []*Eur, period Period) []string {
+	var missing []string
+	for _, p := range periodsOfYear(period.Year(), filingMonthly) {
+		start, end := Date{int(p.Year()), int(p.(Month).month), 1}, periodEnd(p)
+		if !period.includes(start) {
+			continue
+		}
+		if !slices.ContainsFunc(jes, func(e *Eur) bool {
+			return e.Start.compare(start) <= 0 && e.End.compare(end) >= 0
+		}) {
+			missing = append(missing, periodLabel(p))
+		}
+	}
+	return missing
+}
+
 // prepareAccountInfo consolidates the information on tax accounts into
 // the `accountInfo` map
 func (e *Eur) prepareAccountInfo() {
@@ -130,7 +212,7 @@ func (p *Payment) getValue() Cents {
 }
 
 // getNetAmount returns the net amount of this Payment, i.e. without taxes.
-func (p *Payment) getNetAmount(perc int) Cents {
+func (p *Payment) getNetAmount(perc Rate) Cents {
 	val := p.getValue()
 
 	if !p.isIncludingTax() || perc == 0 {
@@ -141,7 +223,7 @@ func (p *Payment) getNetAmount(perc int) Cents {
 }
 
 // getTax returns the taxes of this Payment.
-func (p *Payment) getTax(perc int) Cents {
+func (p *Payment) getTax(perc Rate) Cents {
 	if perc == 0 {
 		return Cents(0)
 	}
@@ -156,12 +238,34 @@ func (p *Payment) getTax(perc int) Cents {
 	return val.Percentage(perc)
 }
 
+// carriedOver returns whether the receipt is a depreciation plan carried over from an earlier business year.
+// This is decided by the purchase: JES dates carried over plans on the first day of the business year,
+// so the asset has been bought earlier if the receipt is dated on or before that day, or its depreciation
+// started before. Assets bought later in this business year are not carried over, even if their depreciation
+// starts only in the next one. An asset really bought on the first day is marked in the sidecar file.
+func (e *Eur) carriedOver(r *Receipt) bool {
+	if r.DepreciationDate == nil {
+		return false
+	}
+	if r.info.CarriedOver != nil {
+		return *r.info.CarriedOver
+	}
+	return r.Date.compare(e.Start) <= 0 || r.DepreciationDate.compare(e.Start) < 0
+}
+
+// booked returns whether the receipt counts for VAT: under Istversteuerung only once paid,
+// under Sollversteuerung already with the invoice.
+// Receipts of the time as Kleinunternehmer never count.
+func (e *Eur) booked(r *Receipt) bool {
+	return (r.Paid || e.accrual) && !e.beforeSwitch(r)
+}
+
 func (e *Eur) payments(period Period) iter.Seq[*Payment] {
 	return func(yield func(*Payment) bool) {
 		for _, r := range e.Receipts {
-			if r.Paid && period.includes(r.Date) {
-				if r.DepreciationDate != nil && r.DepreciationDate.Year != e.Year() {
-					// receipt is a depreciation starting in another year
+			if e.booked(r) && period.includes(r.Date) {
+				if e.carriedOver(r) {
+					// the input tax has been claimed with the purchase in an earlier year
 					continue
 				}
 				for _, p := range r.Payments {
@@ -174,6 +278,79 @@ func (e *Eur) payments(period Period) iter.Seq[*Payment] {
 					}
 				}
 			}
+
+			if e.writtenOff(r) && period.includes(r.info.WrittenOff) {
+				// §17 UStG: the uncollectible receipt is reversed in the period of the write-off
+				for _, p := range r.Payments {
+					if _, isTaxBookingAccount := e.taxBookingAccounts[p.Account]; !isTaxBookingAccount {
+						if !yield(p.negated()) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TaxBooking is a payment to (positive) or a refund from (negative) the Finanzamt, as booked in JES.
+type TaxBooking struct {
+	Date    Date
+	Amount  Cents
+	Receipt int
+}
+
+// taxBookings returns the bookings on the tax booking accounts in the given period, ordered by date.
+// Bookings on the configured refund accounts are refunds, all others are payments.
+func (e *Eur) taxBookings(period Period) []TaxBooking {
+	var bookings []TaxBooking
+
+	for _, r := range e.Receipts {
+		if !r.Paid || !period.includes(r.Date) {
+			continue
+		}
+		for _, p := range r.Payments {
+			if _, ok := e.taxBookingAccounts[p.Account]; !ok {
+				continue
+			}
+
+			amount := p.getValue()
+			if slices.Contains(e.refundAccounts, p.Account) {
+				amount = -amount
+			}
+			bookings = append(bookings, TaxBooking{r.Date, amount, r.Number})
+		}
+	}
+
+	slices.SortStableFunc(bookings, func(a, b TaxBooking) int {
+		return a.Date.compare(b.Date)
+	})
+	return bookings
+}
+
+// taxPayments iterates over all payments in the given period together with their tax accounts.
+// A payment may be yielded twice, if it has both an incoming and an outgoing tax account.
+func (e *Eur) taxPayments(period Period) iter.Seq2[*Payment, TaxAccount] {
+	return func(yield func(*Payment, TaxAccount) bool) {
+		for p := range e.payments(period) {
+			if p.Incoming != 0 {
+				if !yield(p, p.Incoming) {
+					return
+				}
+			}
+			if p.Outgoing != 0 {
+				if !yield(p, p.Outgoing) {
+					return
+				}
+			}
+			if p.Incoming == 0 && p.Outgoing == 0 {
+				// private use may be booked without tax account
+				if rule, ok := e.privateUseRule(p); ok {
+					if !yield(p, rule.account()) {
+						return
+					}
+				}
+			}
 		}
 	}
 }
@@ -181,14 +358,132 @@ func (e *Eur) payments(period Period) iter.Seq[*Payment] {
 type VatDataEntry struct {
 	Tax       Cents
 	NetAmount Cents
-	Percent   int
+	Percent   Rate
 }
 
 func (v VatDataEntry) Empty() bool {
 	return v.Tax == 0 && v.NetAmount == 0
 }
 
-type VatData map[TaxAccount]VatDataEntry
+// Category refines a tax account for payments that need to be reported in other Kennzahlen than the account's default.
+type Category uint8
+
+const (
+	Regular   Category = iota
+	OtherRate          // taxed at a rate deviating from the account's rate, see `RateChange`
+	// tax-free or non-taxable turnover, see `TaxFreeRule`
+	EUService              // nicht steuerbare sonstige Leistungen im übrigen Gemeinschaftsgebiet
+	IntraCommunityDelivery // innergemeinschaftliche Lieferungen
+	Triangular             // Lieferungen des ersten Abnehmers bei innergemeinschaftlichen Dreiecksgeschäften
+	Export                 // weitere steuerfreie Umsätze mit Vorsteuerabzug, z.B. Ausfuhrlieferungen
+	TaxFreeNoDeduction     // steuerfreie Umsätze ohne Vorsteuerabzug
+	NotTaxable             // übrige nicht steuerbare Umsätze
+	OSS                    // B2C sales to other EU countries reported in the OSS return, see `OSSRule`
+	// further §13b UStG categories besides Kz 46/47, named after their Kennzahl, see `ReverseChargeRule`
+	ReverseCharge52
+	ReverseCharge73
+	ReverseCharge78
+	ReverseCharge84
+	PrivateUse    // unentgeltliche Wertabgaben, see `PrivateUseRule`
+	NonDeductible // non-deductible part of the input tax, see `DeductionRule`
+)
+
+func (c Category) String() string {
+	switch c {
+	case Regular:
+		return "Reg"
+	case OtherRate:
+		return "Oth"
+	case EUService:
+		return "EUS"
+	case IntraCommunityDelivery:
+		return "IGL"
+	case Triangular:
+		return "Dre"
+	case Export:
+		return "Exp"
+	case TaxFreeNoDeduction:
+		return "oVA"
+	case NotTaxable:
+		return "NSt"
+	case OSS:
+		return "OSS"
+	case ReverseCharge52:
+		return "R52"
+	case ReverseCharge73:
+		return "R73"
+	case ReverseCharge78:
+		return "R78"
+	case ReverseCharge84:
+		return "R84"
+	case PrivateUse:
+		return "PRV"
+	case NonDeductible:
+		return "NDE"
+	default:
+		return "Unknown"
+	}
+}
+
+// VatKey identifies an entry of VatData.
+type VatKey struct {
+	Account  TaxAccount
+	Category Category
+}
+
+type VatData map[VatKey]VatDataEntry
+
+// Merge adds all entries of `other` to the VatData.
+func (v VatData) Merge(other VatData) {
+	for key, entry := range other {
+		vd := v[key]
+		vd.Tax += entry.Tax
+		vd.NetAmount += entry.NetAmount
+		vd.Percent = entry.Percent
+		v[key] = vd
+	}
+}
+
+// classify determines the VatKey and the tax rate of the payment booked on the given tax account.
+// Payments with a deviating rate are moved to the account regularly having that rate, if any.
+// Otherwise, they are reported as `OtherRate`.
+// OSS sales, tax-free turnover and §13b services are categorized according to the rules from the config.
+func (e *Eur) classify(p *Payment, acc TaxAccount) (VatKey, Rate) {
+	key := VatKey{Account: acc}
+
+	if rule, ok := e.ossRule(p, acc); ok {
+		key.Category = OSS
+		return key, cmp.Or(rule.Percent, e.accountInfo[acc].Percent)
+	}
+
+	if e.isTaxFreeAccount(acc) {
+		key.Category = e.taxFreeCategory(p, acc)
+		if key.Category != Regular {
+			// all tax-free accounts are reported like the default one
+			key.Account = taxFreeAccount
+		}
+		return key, 0
+	}
+
+	percent, deviating := e.rate(p, acc)
+	if deviating {
+		if sibling, ok := e.sibling(acc, percent); ok {
+			key.Account = sibling
+		} else if hasMapping(key.Account, OtherRate) {
+			key.Category = OtherRate
+		}
+	}
+
+	if key.Category == Regular {
+		key.Category = e.reverseChargeCategory(p, key.Account)
+	}
+
+	if key.Category == Regular && e.isPrivateUse(p, key.Account) {
+		key.Category = PrivateUse
+	}
+
+	return key, percent
+}
 
 // VatData returns amount and vat amount for each account in the given period.
 func (e *Eur) VatData(period Period) VatData {
@@ -200,31 +495,37 @@ func (e *Eur) VatData(period Period) VatData {
 	}
 
 	perAccount := func(p paymentWithAccount) {
-		acc := e.accountInfo[p.acc]
-		taxDiff := p.getTax(acc.Percent)
-		amountDiff := p.getNetAmount(acc.Percent)
+		key, percent := e.classify(p.Payment, p.acc)
+		taxDiff := p.getTax(percent)
+		amountDiff := p.getNetAmount(percent)
 
-		debug("Kto %02d/%02d (#%d):\t%s / %s", p.acc, p.Account, p.receipt.Number,
+		debug("Kto %02d/%02d (#%d, %s %s%%):\t%s / %s", p.acc, p.Account, p.receipt.Number, key.Category, percent,
 			amountDiff.Format("%3d.%02d EUR"),
 			taxDiff.Format("%3d.%02d EUR"))
 
-		vd := vatData[p.acc]
+		if share, ok := e.deductionShare(p.Payment, key.Account); ok {
+			var nonDeductible Cents
+			taxDiff, nonDeductible = splitDeductible(taxDiff, share)
+
+			ndKey := VatKey{key.Account, NonDeductible}
+			nd := vatData[ndKey]
+			nd.Tax += nonDeductible
+			nd.Percent = percent
+			vatData[ndKey] = nd
+		}
+
+		vd := vatData[key]
 		vd.Tax += taxDiff
 		vd.NetAmount += amountDiff
-		vd.Percent = acc.Percent
-		vatData[p.acc] = vd
+		vd.Percent = percent
+		vatData[key] = vd
 	}
 
 	// to help debugging, we sort the payments by account number and receipt number
 	payments := make([]paymentWithAccount, 0, 100)
 
-	for p := range e.payments(period) {
-		if p.Incoming != 0 {
-			payments = append(payments, paymentWithAccount{p, p.Incoming})
-		}
-		if p.Outgoing != 0 {
-			payments = append(payments, paymentWithAccount{p, p.Outgoing})
-		}
+	for p, acc := range e.taxPayments(period) {
+		payments = append(payments, paymentWithAccount{p, acc})
 	}
 
 	slices.SortFunc(payments, func(a, b paymentWithAccount) int {
@@ -235,14 +536,70 @@ func (e *Eur) VatData(period Period) VatData {
 		perAccount(p)
 	}
 
+	for key, vd := range vatData {
+		switch {
+		case vd.Empty():
+		case key.Category == Regular && e.isTaxFreeAccount(key.Account):
+			log.Printf("WARNING: %s of tax-free turnover on account %d is not classified and therefore not reported. "+
+				"See `taxFree` in the config.", vd.NetAmount, key.Account)
+		case key.Category == OSS:
+			// reported in the OSS return instead
+		case !hasMapping(key.Account, key.Category):
+			log.Printf("WARNING: %s on account %d (%s) is not mapped to any Kennzahl and therefore not reported.",
+				vd.NetAmount, key.Account, key.Category)
+		}
+	}
+
 	return vatData
 }
 
-func (e *Eur) Validate() {
-	if e.Start.Year != e.End.Year {
-		log.Fatalf("JES spans multiple years. This is not supported.")
+// mergedVatData returns the VatData of all given JES files combined, as well as the VatData of each single file.
+func mergedVatData(jes []*Eur, period Period) (VatData, []VatData) {
+	merged := make(VatData)
+	perFile := make([]VatData, len(jes))
+
+	for i, e := range jes {
+		if len(jes) > 1 {
+			debug("=== %s ===", e.file)
+		}
+		perFile[i] = e.VatData(period)
+		merged.Merge(perFile[i])
 	}
 
+	return merged, perFile
+}
+
+// checkConsistency ensures that tax accounts used in several JES files have the same tax rate everywhere.
+func checkConsistency(jes []*Eur) {
+	seen := make(map[TaxAccount]*Eur)
+
+	for _, e := range jes {
+		for acc, info := range e.accountInfo {
+			other, ok := seen[acc]
+			if !ok {
+				seen[acc] = e
+				continue
+			}
+
+			if otherPercent := other.accountInfo[acc].Percent; otherPercent != info.Percent {
+				log.Fatalf("Inconsistent tax rate for tax account %d: %s%% in '%s' vs %s%% in '%s'",
+					acc, otherPercent, other.file, info.Percent, e.file)
+			}
+		}
+	}
+}
+
+// accountPercent returns the tax rate of the given tax account in the first JES file that knows the account.
+func accountPercent(jes []*Eur, acc TaxAccount) Rate {
+	for _, e := range jes {
+		if info, ok := e.accountInfo[acc]; ok {
+			return info.Percent
+		}
+	}
+	return 0
+}
+
+func (e *Eur) Validate() {
 	// Check for unsupported tax accounts
 	knownAccounts := make(map[TaxAccount]struct{})
 	for _, m := range mappings {
@@ -254,7 +611,7 @@ func (e *Eur) Validate() {
 			return
 		}
 
-		if _, ok := knownAccounts[acc]; !ok {
+		if _, ok := knownAccounts[acc]; !ok && !e.isTaxFreeAccount(acc) && !e.isOSSAccount(acc) {
 			log.Fatalf("Unsupported tax account '%d'", acc)
 		}
 	}
@@ -268,7 +625,7 @@ func (e *Eur) Validate() {
 	}
 }
 
-func readJesFile(jesFile string) *Eur {
+func readJesFile(jesFile string, conf *Config) *Eur {
 	// JES stores the file as a ZIP archive
 	zipF, err := zip.OpenReader(jesFile)
 	if err != nil {
@@ -296,13 +653,25 @@ func readJesFile(jesFile string) *Eur {
 	defer f.Close()
 
 	// Decode XML data
-	eur := new(Eur)
+	eur := &Eur{file: jesFile}
 	decoder := xml.NewDecoder(f)
 	if err = decoder.Decode(eur); err != nil {
 		log.Fatalf("Decoding '%s': %v", jesFile, err)
 	}
 
 	eur.prepareAccountInfo()
+	eur.attachSidecar(readSidecar(jesFile))
+	eur.prepareRates(conf.Rates)
+	eur.prepareTaxFree(conf.TaxFree)
+	eur.prepareOSS(conf.OSS)
+	eur.prepareReverseCharge(conf.ReverseCharge)
+	eur.preparePrivateUse(conf.PrivateUse)
+	eur.prepareDeduction(conf.Deduction)
+	eur.refundAccounts = conf.RefundAccounts
+	eur.accrual = conf.Sollversteuerung
+	eur.smallBusinessUntil = conf.smallBusinessUntil()
+	eur.checkWrittenOff()
+	eur.checkCarriedOver()
 
 	return eur
 }
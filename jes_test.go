@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newPayment(acc TaxAccount, account int, value string) *Payment {
+	p := &Payment{Incoming: acc, Account: account}
+	p.Amount.Value = value
+	return p
+}
+
+func TestVatDataMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		v, other VatData
+		want     VatData
+	}{
+		{"empty",
+			VatData{},
+			VatData{{500, Regular}: {NetAmount: 100_00, Tax: 19_00, Percent: 19 * pct}},
+			VatData{{500, Regular}: {NetAmount: 100_00, Tax: 19_00, Percent: 19 * pct}}},
+		{"disjoint keys",
+			VatData{{500, Regular}: {NetAmount: 100_00, Tax: 19_00, Percent: 19 * pct}},
+			VatData{{510, Regular}: {NetAmount: 200_00, Tax: 14_00, Percent: 7 * pct}},
+			VatData{
+				{500, Regular}: {NetAmount: 100_00, Tax: 19_00, Percent: 19 * pct},
+				{510, Regular}: {NetAmount: 200_00, Tax: 14_00, Percent: 7 * pct},
+			}},
+		{"overlapping keys",
+			VatData{
+				{500, Regular}:   {NetAmount: 100_00, Tax: 19_00, Percent: 19 * pct},
+				{500, OtherRate}: {NetAmount: 50_00, Tax: 8_00, Percent: 16 * pct},
+			},
+			VatData{
+				{500, Regular}:   {NetAmount: -30_00, Tax: -5_70, Percent: 19 * pct},
+				{500, OtherRate}: {NetAmount: 10_00, Tax: 1_60, Percent: 16 * pct},
+			},
+			VatData{
+				{500, Regular}:   {NetAmount: 70_00, Tax: 13_30, Percent: 19 * pct},
+				{500, OtherRate}: {NetAmount: 60_00, Tax: 9_60, Percent: 16 * pct},
+			}},
+		{"same category, different accounts",
+			VatData{{500, OSS}: {NetAmount: 100_00, Tax: 20_00, Percent: 20 * pct}},
+			VatData{{510, OSS}: {NetAmount: 100_00, Tax: 10_00, Percent: 10 * pct}},
+			VatData{
+				{500, OSS}: {NetAmount: 100_00, Tax: 20_00, Percent: 20 * pct},
+				{510, OSS}: {NetAmount: 100_00, Tax: 10_00, Percent: 10 * pct},
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.v.Merge(tt.other)
+			if !maps.Equal(tt.v, tt.want) {
+				t.Errorf("Merge = %v, want %v", tt.v, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergedVatData(t *testing.T) {
+	accounts := map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}, 510: {Number: 510, Percent: 7 * pct}}
+
+	first := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}, accountInfo: accounts}
+	first.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 3, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100")}},
+		{Number: 2, Date: Date{2024, 4, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "1000")}},
+	}
+	first.Validate()
+
+	second := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}, accountInfo: accounts}
+	second.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 3, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "200")}},
+		{Number: 2, Date: Date{2024, 3, 20}, Paid: true, Payments: []*Payment{newPayment(510, 8300, "50")}},
+	}
+	second.Validate()
+
+	merged, perFile := mergedVatData([]*Eur{first, second}, Month{2024, 3})
+
+	want := VatData{
+		{500, Regular}: {NetAmount: 300_00, Tax: 57_00, Percent: 19 * pct},
+		{510, Regular}: {NetAmount: 50_00, Tax: 3_50, Percent: 7 * pct},
+	}
+	if !maps.Equal(merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+
+	if len(perFile) != 2 || perFile[0][VatKey{500, Regular}].NetAmount != 100_00 || perFile[1][VatKey{500, Regular}].NetAmount != 200_00 {
+		t.Errorf("per file = %v, want 100.00 EUR and 200.00 EUR on account 500", perFile)
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	eur := func(file string, percent Rate) *Eur {
+		return &Eur{file: file, accountInfo: map[TaxAccount]Account{
+			500: {Number: 500, Percent: percent},
+			510: {Number: 510, Percent: 7 * pct},
+		}}
+	}
+
+	if os.Getenv("JESVA_TEST_FATAL") == "1" {
+		checkConsistency([]*Eur{eur("a.eux", 19*pct), eur("b.eux", 16*pct)})
+		return
+	}
+
+	// consistent files pass
+	checkConsistency([]*Eur{eur("a.eux", 19*pct), eur("b.eux", 19*pct), {file: "c.eux"}})
+
+	// inconsistent rates are fatal, so run the check in a subprocess
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCheckConsistency$")
+	cmd.Env = append(os.Environ(), "JESVA_TEST_FATAL=1")
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Success() {
+		t.Fatalf("inconsistent tax rates did not abort: %v", err)
+	}
+	if want := "Inconsistent tax rate for tax account 500: 19% in 'a.eux' vs 16% in 'b.eux'"; !strings.Contains(string(out), want) {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestAccrual(t *testing.T) {
+	newEur := func(accrual bool) *Eur {
+		e := &Eur{
+			Start:       Date{2024, 1, 1},
+			End:         Date{2024, 12, 31},
+			accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}, 100: {Number: 100, Percent: 19 * pct}},
+			accrual:     accrual,
+		}
+		e.Receipts = []*Receipt{
+			// invoice paid in a later month is still dated on the invoice
+			{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100")}},
+			// unpaid invoice
+			{Number: 2, Date: Date{2024, 1, 20}, Payments: []*Payment{newPayment(500, 8400, "200")}},
+			// unpaid asset
+			{Number: 3, Date: Date{2024, 2, 1}, DepreciationDate: &Date{2024, 2, 1},
+				Payments: []*Payment{newPayment(100, 4830, "1000")}},
+		}
+		e.Validate()
+		return e
+	}
+
+	tests := []struct {
+		accrual  bool
+		period   Period
+		net      Cents
+		inputTax Cents
+	}{
+		{false, Month{2024, 1}, 100_00, 0},
+		{false, Month{2024, 2}, 0, 0},
+		{true, Month{2024, 1}, 300_00, 0},
+		{true, Month{2024, 2}, 0, 190_00},
+	}
+
+	for _, tt := range tests {
+		vd := newEur(tt.accrual).VatData(tt.period)
+		if net, tax := vd[VatKey{500, Regular}].NetAmount, vd[VatKey{100, Regular}].Tax; net != tt.net || tax != tt.inputTax {
+			t.Errorf("VatData(%s, accrual=%v) = %s / %s input tax, want %s / %s",
+				periodLabel(tt.period), tt.accrual, net, tax, tt.net, tt.inputTax)
+		}
+	}
+
+	if got := assetData(&Config{}, []*Eur{newEur(false)}); len(got) != 1 || got[0].Note != assetUnpaid {
+		t.Errorf("assetData() under Istversteuerung = %v, want the asset unpaid", got)
+	}
+	if got := assetData(&Config{}, []*Eur{newEur(true)}); len(got) != 1 || got[0].Note != "" || got[0].InputTax != 190_00 {
+		t.Errorf("assetData() under Sollversteuerung = %v, want the input tax claimed", got)
+	}
+}
+
+func TestMissingMonths(t *testing.T) {
+	calendar := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}}
+	fiscal := &Eur{Start: Date{2023, 7, 1}, End: Date{2024, 6, 30}}
+	nextFiscal := &Eur{Start: Date{2024, 7, 1}, End: Date{2025, 6, 30}}
+
+	tests := []struct {
+		name   string
+		jes    []*Eur
+		period Period
+		want   []string
+	}{
+		{"calendar year", []*Eur{calendar}, Year(2024), nil},
+		{"fiscal year", []*Eur{fiscal}, Year(2024),
+			[]string{"2024-07", "2024-08", "2024-09", "2024-10", "2024-11", "2024-12"}},
+		{"consecutive fiscal years", []*Eur{fiscal, nextFiscal}, Year(2024), nil},
+		{"quarter within fiscal year", []*Eur{fiscal}, Quarter{2024, 2}, nil},
+		{"quarter after fiscal year", []*Eur{fiscal}, Quarter{2024, 3}, []string{"2024-07", "2024-08", "2024-09"}},
+		{"month after fiscal year", []*Eur{fiscal}, Month{2024, 7}, []string{"2024-07"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingMonths(tt.jes, tt.period); !slices.Equal(got, tt.want) {
+				t.Errorf("missingMonths(%s) = %v, want %v", periodLabel(tt.period), got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 const (
@@ -32,6 +35,49 @@ type Config struct {
 		Telephone string `json:"tel"`
 		Mail      string `json:"mail"`
 	}
+	Rates         []RateChange        `json:"rates"`
+	TaxFree       []TaxFreeRule       `json:"taxFree"`
+	OSS           []OSSRule           `json:"oss"`
+	ReverseCharge []ReverseChargeRule `json:"reverseCharge"`
+	PrivateUse    []PrivateUseRule    `json:"privateUse"`
+	// Partial input tax deduction per booking account
+	Deduction []DeductionRule `json:"partialDeduction"`
+	// Assets subject to the Vorsteuerberichtigung (§15a UStG)
+	InputTaxCorrections []InputTaxCorrection `json:"vorsteuerberichtigung"`
+	// Filing frequency of the UStVA: "monthly" or "quarterly"
+	Filing string `json:"filing"`
+	// Whether a Dauerfristverlängerung has been granted
+	Dfv bool `json:"dauerfristverlaengerung"`
+	// Sondervorauszahlung per year, overrides the amount stored by the `dfv` command
+	Svz map[int]Cents `json:"sondervorauszahlung"`
+	// Booking accounts for tax refunds from the Finanzamt. All other tax booking accounts are considered payments.
+	RefundAccounts []int `json:"refundAccounts"`
+	// Whether taxes are computed on invoices (Sollversteuerung) instead of payments (Istversteuerung)
+	Sollversteuerung bool `json:"sollversteuerung"`
+	// Kleinunternehmerregelung (§19 UStG)
+	SmallBusiness *SmallBusiness `json:"kleinunternehmer"`
+	// Derivation of the tax of Kennzahlen like 81/86: "total" (default, like ELSTER) or "receipt"
+	TaxComputation string `json:"taxComputation"`
+}
+
+const (
+	filingMonthly   = "monthly"
+	filingQuarterly = "quarterly"
+)
+
+// isFinalPeriod returns whether the period is the last UStVA period of its year under the configured filing frequency.
+func (c *Config) isFinalPeriod(p Period) bool {
+	switch c.Filing {
+	case filingMonthly:
+		if _, ok := p.(Quarter); ok {
+			return false
+		}
+	case filingQuarterly:
+		if _, ok := p.(Quarter); !ok {
+			return false
+		}
+	}
+	return isFinalPeriod(p)
 }
 
 // readConfig loads the configuration from the location specified in `configName`
@@ -62,6 +108,20 @@ func readConfig() *Config {
 		log.Fatalf("Parsing config at '%s': %v", name, err)
 	}
 
+	switch config.Filing {
+	case "", filingMonthly, filingQuarterly:
+	default:
+		log.Fatalf("Invalid filing frequency '%s' in config at '%s', expected '%s' or '%s'.",
+			config.Filing, name, filingMonthly, filingQuarterly)
+	}
+
+	switch config.TaxComputation {
+	case "", taxPerTotal, taxPerReceipt:
+	default:
+		log.Fatalf("Invalid tax computation '%s' in config at '%s', expected '%s' or '%s'.",
+			config.TaxComputation, name, taxPerTotal, taxPerReceipt)
+	}
+
 	return config
 }
 
@@ -73,6 +133,151 @@ func debug(format string, args ...any) {
 	}
 }
 
+const usage = `Usage: %s [options] <jes.file> [<jes.file>...] <period>
+
+<period> is either:
+	* 1,...,12 for a month
+	* Q1,...,Q4 for a quarter
+	* 2024-03 or 2024Q1 for a month or quarter in a specific year
+	* 3-5 for a range of months
+	* prev, prev-quarter, current for the previous month, the previous quarter or the current month
+	* ytd for January up to the current month
+
+Multiple JES files (*.eux) are merged into one UStVA. JES files of earlier years only contribute
+their receipts written off in the period.
+
+Possible options:
+	-d: Enable debug output
+	-svz amount: Take into account a Sondervorauszahlung. Only allowed for the final period of the year.
+	             Without it, the configured or stored Sondervorauszahlung is applied to the final period.
+	-date YYYY-MM-DD: Fixed creation date of the generated files, for reproducible output.
+	                  Defaults to SOURCE_DATE_EPOCH, if set, and to the current date otherwise.
+
+Additionally, there exists the year-end mode:
+> %[1]s [options] <jes.file> [<jes.file>...] <year> <xml-file 1, xml-file 2, ..., xml-file n>
+	Pass the JES file of the following year as well, to include the tax payments for <year> booked there.
+
+Further commands:
+> %[1]s [options] zm <jes.file> [<jes.file>...] <period>
+	Zusammenfassende Meldung as CSV for the BZSt upload.
+> %[1]s [options] oss <jes.file> [<jes.file>...] <quarter>
+	One-Stop-Shop return as CSV for the BZSt upload.
+> %[1]s [options] dfv <jes.file> [<jes.file>...] <year>
+> %[1]s [options] dfv <year> <xml-file 1, xml-file 2, ..., xml-file n>
+	Dauerfristverlängerung for <year>, with the Sondervorauszahlung computed from the JES or UStVA XML files
+	of the previous year. The Sondervorauszahlung is stored and applied to the final period of <year>.
+> %[1]s [options] reconcile <jes.file> [<jes.file>...] <year>
+	Compares the tax payments booked in JES to the Zahllast of each UStVA period of <year>.
+	Pass the JES file of the following year as well, to include the payments booked there.
+> %[1]s [options] deadlines [-ics <file>] <year> [<xml-file>... | <jes.file>...]
+	Lists the due dates of all UStVAs of <year>. Their status is taken from the given filed UStVA and DFV XML files
+	and the tax payments booked in the given JES files. With -ics, the dates are also exported to a calendar.
+> %[1]s [options] assets <jes.file> [<jes.file>...]
+	Lists all receipts with a depreciation plan and the UStVA period their input tax is claimed in.
+> %[1]s [options] kleinunternehmer <jes.file> [<jes.file>...] <year>
+	Checks the turnover limits of the Kleinunternehmerregelung (§19 UStG) for <year> and shows the remaining headroom.
+	Pass the JES file of the previous year as well, to check its limit.
+> %[1]s [options] trend [-csv] <jes.file> [<jes.file>...] <year>
+	Shows all Kennzahlen and the Zahllast per UStVA period of <year> with running totals.
+	Pass the JES file of the previous year as well, to compare each period to the same one of the prior year.
+`
+
+// commands maps the name of a command to its implementation.
+// They are called with the remaining arguments after the command name.
+var commands = map[string]func(conf *Config, args []string){
+	"zm":               cmdZM,
+	"oss":              cmdOSS,
+	"dfv":              cmdDFV,
+	"reconcile":        cmdReconcile,
+	"deadlines":        cmdDeadlines,
+	"assets":           cmdAssets,
+	"kleinunternehmer": cmdSmallBusiness,
+	"trend":            cmdTrend,
+}
+
+// splitJesArgs splits the arguments into the leading JES files and the remaining arguments.
+// The first argument is always considered a JES file, further ones only if they have the JES extension.
+func splitJesArgs(args []string) ([]string, []string) {
+	jesFiles := []string{args[0]}
+	args = args[1:]
+	for len(args) > 1 && strings.EqualFold(filepath.Ext(args[0]), jesExt) {
+		jesFiles = append(jesFiles, args[0])
+		args = args[1:]
+	}
+	return jesFiles, args
+}
+
+// periodArg parses the period given on the commandline and aborts on error.
+func periodArg(periodStr string) Period {
+	period, err := parsePeriod(periodStr)
+	if err != nil {
+		log.Fatalf("Parsing period: %v", err)
+	}
+	return period
+}
+
+// yearArg parses the year given on the commandline and aborts on error.
+func yearArg(yearStr string) Year {
+	year, err := parseYear(yearStr)
+	if err != nil {
+		log.Fatalf("Parsing year: %v", err)
+	}
+	return year
+}
+
+// loadJes reads and validates all given JES files.
+func loadJes(conf *Config, jesFiles []string) []*Eur {
+	jes := make([]*Eur, len(jesFiles))
+	for i, jesFile := range jesFiles {
+		jes[i] = readJesFile(jesFile, conf)
+		jes[i].Validate()
+	}
+	checkConsistency(jes)
+	return jes
+}
+
+// resolvePeriod binds the period to the year of the JES files, if not explicitly given.
+// It also ensures that all JES files cover the period and that all of its months are covered completely.
+// JES files of earlier years are only allowed as source of their receipts written off in the period (§17 UStG),
+// those of later years only for the year-end mode as source of the tax payments for the year.
+func resolvePeriod(period Period, periodStr string, jes []*Eur) Period {
+	if period.Year() == 0 {
+		latest := slices.MaxFunc(jes, func(a, b *Eur) int { return a.End.compare(b.End) })
+		if latest.Start.Year != latest.End.Year {
+			log.Fatalf("JES spans multiple years (%d-%d). Please specify the year of the period, e.g. '%d-%s'.",
+				latest.Start.Year, latest.End.Year, latest.End.Year, periodStr)
+		}
+		period = period.inYear(Year(latest.Year()))
+	}
+
+	_, isYear := period.(Year)
+	covered := false
+	for _, e := range jes {
+		switch {
+		case e.coversYear(int(period.Year())):
+			covered = true
+		case e.End.Year < int(period.Year()):
+			log.Printf("JES file '%s' (%d-%d) precedes the period '%s' and is only used for receipts written off in it.",
+				e.file, e.Start.Year, e.End.Year, periodStr)
+		case e.Start.Year > int(period.Year()) && isYear:
+			log.Printf("JES file '%s' (%d-%d) follows the year '%s' and is only used for its tax payments.",
+				e.file, e.Start.Year, e.End.Year, periodStr)
+		default:
+			log.Fatalf("Period '%s' is not covered by the JES file '%s' (%d-%d).",
+				periodStr, e.file, e.Start.Year, e.End.Year)
+		}
+	}
+	if !covered {
+		log.Fatalf("Period '%s' is not covered by any of the JES files.", periodStr)
+	}
+	if missing := missingMonths(jes, period); len(missing) > 0 {
+		log.Fatalf("Period '%s' is only partly covered by the JES files, missing: %s. "+
+			"Please also pass the JES files of the other business years.", periodStr, strings.Join(missing, ", "))
+	}
+
+	return period
+}
+
 func main() {
 	log.SetFlags(0) // no prefix for logging
 	log.SetOutput(os.Stderr)
@@ -96,72 +301,58 @@ cmdparsing:
 				log.Fatalf("Parsing -svz option: %v", err)
 			}
 
+			args = args[2:]
+		case "-date":
+			if len(args) < 2 || len(args[1]) == 0 {
+				log.Fatalf("Missing date for -date option.")
+			}
+			var err error
+			if creationDate, err = time.Parse(dateLayout, args[1]); err != nil {
+				log.Fatalf("Parsing -date option: %v", err)
+			}
+
 			args = args[2:]
 		default:
 			break cmdparsing
 		}
 	}
 
-	if len(args) < 2 {
-		log.Fatalf(`Usage: %s [options] <jes.file> <period>
-
-<period> is either:
-	* 1,...,12 for a month
-	* Q1,...,Q4 for a quarter
-
-Possible options:
-	-d: Enable debug output
-	-svz amount: Take into account a Sondervorauszahlung.
-
-Additionally, there exists the year-end mode:
-> %[1]s [options] <jes.file> <year> <xml-file 1, xml-file 2, ..., xml-file n>
-`, os.Args[0])
-	}
-
-	jesFile := args[0]
-	periodStr := args[1]
-
-	var period Period
-	if periodStr[0] == 'q' || periodStr[0] == 'Q' { // Quarter
-		switch periodStr[1] {
-		case '1':
-			period = Q1
-		case '2':
-			period = Q2
-		case '3':
-			period = Q3
-		case '4':
-			period = Q4
-		default:
-			log.Fatalf("Unknown quarter '%s'", periodStr)
+	if creationDate.IsZero() {
+		epoch, ok, err := sourceDateEpoch()
+		if err != nil {
+			log.Fatalf("%v", err)
 		}
-	} else if startStr, endStr, found := strings.Cut(periodStr, "-"); found { // Month range
-		start := parseMonth(startStr)
-		end := parseMonth(endStr)
+		if ok {
+			creationDate = epoch
+		}
+	}
 
-		if end <= start {
-			log.Fatalf("End month must be larger than starting month.")
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			cmd(readConfig(), args[1:])
+			return
 		}
+	}
 
-		period = Months{start, end}
-	} else if len(periodStr) <= 2 { // single month
-		period = parseMonth(periodStr)
-	} else if len(periodStr) == 4 { // year
-		period = parseYear(periodStr)
-	} else {
-		log.Fatalf("Unknown period '%s'", periodStr)
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
 	}
 
+	jesFiles, args := splitJesArgs(args)
+	periodStr := args[0]
+	period := periodArg(periodStr)
+
 	conf := readConfig()
-	jes := readJesFile(jesFile)
-	jes.Validate()
+	jes := loadJes(conf, jesFiles)
+	period = resolvePeriod(period, periodStr, jes)
 
 	if _, ok := period.(Year); ok {
 		// UStE
-		xmls := args[2:]
-		OutputUStE(jes, period, xmls)
+		xmls := args[1:]
+		OutputUStE(conf, jes, period, xmls)
 	} else {
 		// UStVA
+		svz = svzForPeriod(conf, period, periodStr, svz)
 		BuildVatFile(conf, jes, period, svz)
 	}
 }
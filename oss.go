@@ -0,0 +1,189 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OSSRule marks payments as OSS sales, i.e. B2C sales to consumers in other EU countries.
+// These are not part of the UStVA, but reported quarterly in the One-Stop-Shop return.
+type OSSRule struct {
+	// Tax account; if not given, BookingAccounts must be set
+	Account TaxAccount `json:"account"`
+	// Optional: only applies to payments on these booking accounts
+	BookingAccounts []int `json:"bookingAccounts"`
+	// Optional: country of destination, otherwise it is taken from the sidecar of each receipt
+	Country string `json:"country"`
+	// Optional: tax rate of the country of destination, otherwise the rate of the tax account
+	Percent Rate `json:"percent"`
+	// Optional: type of the rate in the country of destination, "standard" or "reduced".
+	// Otherwise, sales on the tax account of 7% are reduced, all others standard.
+	RateType string `json:"rateType"`
+}
+
+// Types of tax rates in the OSS return.
+const (
+	ossStandard = "standard"
+	ossReduced  = "reduced"
+)
+
+// ossVersion are the leading lines of the BZSt CSV format: the version of the format and of its records.
+var ossVersion = [][]string{{"#v2.0"}, {"#ve1.1"}}
+
+// rateType returns the type of the rate of the payment on the given tax account.
+func (r OSSRule) rateType(acc TaxAccount) string {
+	switch {
+	case r.RateType != "":
+		return r.RateType
+	case acc == 510:
+		return ossReduced
+	default:
+		return ossStandard
+	}
+}
+
+func (r OSSRule) applies(acc TaxAccount, bookingAccount int) bool {
+	return (r.Account == 0 || r.Account == acc) &&
+		(len(r.BookingAccounts) == 0 || slices.Contains(r.BookingAccounts, bookingAccount))
+}
+
+// prepareOSS checks the rules for OSS sales.
+func (e *Eur) prepareOSS(rules []OSSRule) {
+	for _, r := range rules {
+		if r.Account == 0 && len(r.BookingAccounts) == 0 {
+			log.Fatalf("OSS rule needs either a tax account or booking accounts.")
+		}
+		switch r.RateType {
+		case "", ossStandard, ossReduced:
+		default:
+			log.Fatalf("Invalid rate type '%s' in OSS rule, expected '%s' or '%s'.", r.RateType, ossStandard, ossReduced)
+		}
+	}
+
+	e.oss = rules
+}
+
+// isOSSAccount returns whether the tax account is explicitly used for OSS sales.
+func (e *Eur) isOSSAccount(acc TaxAccount) bool {
+	return slices.ContainsFunc(e.oss, func(r OSSRule) bool {
+		return r.Account == acc
+	})
+}
+
+// ossRule returns the OSS rule matching the payment on the given tax account, if any.
+func (e *Eur) ossRule(p *Payment, acc TaxAccount) (OSSRule, bool) {
+	for _, r := range e.oss {
+		if r.applies(acc, p.Account) {
+			return r, true
+		}
+	}
+	return OSSRule{}, false
+}
+
+// OSSEntry is one line of the OSS return: the sales to one country at one tax rate.
+type OSSEntry struct {
+	Country  string
+	RateType string
+	Percent  Rate
+	Amount   Cents
+}
+
+func (o OSSEntry) Tax() Cents {
+	return o.Amount.Percentage(o.Percent)
+}
+
+// ossData sums up the OSS sales per country of destination and tax rate.
+func ossData(jes []*Eur, period Period) []OSSEntry {
+	type key struct {
+		country  string
+		rateType string
+		percent  Rate
+	}
+	sums := make(map[key]Cents)
+
+	for _, e := range jes {
+		for p, acc := range e.taxPayments(period) {
+			rule, ok := e.ossRule(p, acc)
+			if !ok {
+				continue
+			}
+
+			_, percent := e.classify(p, acc)
+			country := strings.ToUpper(cmp.Or(p.receipt.info.Country, rule.Country))
+			if len(country) != 2 {
+				log.Fatalf("Receipt #%d in '%s' lacks a valid country of destination. Please add it to '%s'.",
+					p.receipt.Number, e.file, sidecarName(e.file))
+			}
+
+			amount := p.getNetAmount(percent)
+			debug("OSS %s/%s%% (#%d):\t%s", country, percent, p.receipt.Number, amount)
+			sums[key{country, rule.rateType(acc), percent}] += amount
+		}
+	}
+
+	entries := make([]OSSEntry, 0, len(sums))
+	for _, k := range slices.SortedFunc(maps.Keys(sums), func(a, b key) int {
+		return cmp.Or(strings.Compare(a.country, b.country), strings.Compare(a.rateType, b.rateType),
+			cmp.Compare(a.percent, b.percent))
+	}) {
+		if sums[k] != 0 {
+			entries = append(entries, OSSEntry{k.country, k.rateType, k.percent, sums[k]})
+		}
+	}
+	return entries
+}
+
+// WriteOSS writes the OSS return in the CSV format of the BZSt upload.
+// All sales are reported as "Satzart 1", i.e. supplies from Germany.
+func WriteOSS(w io.Writer, entries []OSSEntry) {
+	csvWriter := csv.NewWriter(w)
+	records := slices.Concat(ossVersion, [][]string{
+		{"Satzart", "Land des Verbrauchs", "Steuersatztyp", "Steuersatz", "Steuerbemessungsgrundlage", "Steuerbetrag"},
+	})
+	for _, e := range entries {
+		records = append(records, []string{
+			"1",
+			e.Country,
+			strings.ToUpper(e.RateType),
+			e.Percent.Format("%d.%02d"),
+			e.Amount.Format("%d.%02d"),
+			e.Tax().Format("%d.%02d"),
+		})
+	}
+
+	if err := csvWriter.WriteAll(records); err != nil {
+		log.Fatalf("Writing OSS return: %v", err)
+	}
+
+	var total Cents
+	for _, e := range entries {
+		total += e.Tax()
+	}
+	fmt.Fprintf(os.Stderr, "*** Expected OSS Tax Sum: %s ***\n", total)
+}
+
+func cmdOSS(conf *Config, args []string) {
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jesFiles, args := splitJesArgs(args)
+	periodStr := args[0]
+	period := periodArg(periodStr)
+
+	if _, ok := period.(Quarter); !ok {
+		log.Fatalf("The OSS return is filed per quarter, '%s' is not supported.", periodStr)
+	}
+
+	jes := loadJes(conf, jesFiles)
+	period = resolvePeriod(period, periodStr, jes)
+
+	WriteOSS(os.Stdout, ossData(jes, period))
+}
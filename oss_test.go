@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestOSSData(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		500: {Number: 500, Percent: 19 * pct},
+		510: {Number: 510, Percent: 7 * pct},
+		530: {Number: 530, Percent: 20 * pct},
+	}}
+	e.prepareOSS([]OSSRule{
+		{Account: 530},
+		{Account: 500, BookingAccounts: []int{8340}, Country: "NL", Percent: 21 * pct},
+		{Account: 500, BookingAccounts: []int{8345}, Country: "AT", Percent: 13 * pct, RateType: ossReduced},
+		{Account: 510, Country: "AT", Percent: 10 * pct},
+	})
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, info: ReceiptInfo{Country: "at"},
+			Payments: []*Payment{newPayment(530, 8300, "100")}},
+		{Number: 2, Date: Date{2024, 2, 10}, Paid: true,
+			Payments: []*Payment{newPayment(500, 8340, "200")}},
+		{Number: 3, Date: Date{2024, 3, 10}, Paid: true,
+			Payments: []*Payment{newPayment(500, 8400, "300")}},
+		{Number: 4, Date: Date{2024, 3, 15}, Paid: true,
+			Payments: []*Payment{newPayment(500, 8345, "50")}},
+		{Number: 5, Date: Date{2024, 3, 20}, Paid: true,
+			Payments: []*Payment{newPayment(510, 8300, "40")}},
+	}
+	e.Validate()
+
+	got := ossData([]*Eur{e}, Quarter{2024, 1})
+	want := []OSSEntry{
+		{"AT", ossReduced, 10 * pct, 4000},
+		{"AT", ossReduced, 13 * pct, 5000},
+		{"AT", ossStandard, 20 * pct, 10000},
+		{"NL", ossStandard, 21 * pct, 20000},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ossData() = %v, want %v", got, want)
+	}
+
+	// OSS sales must not show up in the UStVA
+	k := kennzahlenFromVatData(e.VatData(Quarter{2024, 1}))
+	if kz := k[81]; kz == nil || kz.amount != 30000 {
+		t.Errorf("Kz 81 = %v, want 300.00 EUR", kz)
+	}
+	if len(k) != 1 {
+		t.Errorf("Unexpected Kennzahlen: %v", k)
+	}
+}
+
+func TestWriteOSS(t *testing.T) {
+	var buf bytes.Buffer
+	WriteOSS(&buf, []OSSEntry{
+		{"AT", ossReduced, 10 * pct, 4000},
+		{"NL", ossStandard, 21 * pct, 20000},
+	})
+
+	// sample of the BZSt CSV format
+	want := "#v2.0\n" +
+		"#ve1.1\n" +
+		"Satzart,Land des Verbrauchs,Steuersatztyp,Steuersatz,Steuerbemessungsgrundlage,Steuerbetrag\n" +
+		"1,AT,REDUCED,10.00,40.00,4.00\n" +
+		"1,NL,STANDARD,21.00,200.00,42.00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteOSS output = %q, want %q", got, want)
+	}
+}
+
+func TestOSSNotWarned(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	e := &Eur{accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}}}
+	e.prepareOSS([]OSSRule{{BookingAccounts: []int{8340}, Country: "AT", Percent: 20 * pct}})
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8340, "100")}},
+	}
+	e.Validate()
+
+	if vd := e.VatData(Quarter{2024, 1}); vd[VatKey{500, OSS}].NetAmount != 10000 {
+		t.Errorf("OSS sales = %v, want 100.00 EUR", vd)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Unexpected warning: %s", buf.String())
+	}
+}
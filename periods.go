@@ -2,31 +2,51 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// now returns the current time. It is a variable so tests can fix the clock.
+var now = time.Now
+
 type Period interface {
 	includes(Date) bool
 	String() string
+	// Year returns the calendar year of the period. It is 0 if the period has not been bound to a year yet.
+	Year() Year
+	// inYear returns the period bound to the given calendar year.
+	inYear(Year) Period
 }
 
-type Month uint8
+type Month struct {
+	year  Year
+	month uint8
+}
 
 func (m Month) includes(d Date) bool {
-	return d.Month == int(m)
+	return d.Year == int(m.year) && d.Month == int(m.month)
 }
 
 func (m Month) String() string {
-	return fmt.Sprintf("%02d", m)
+	return fmt.Sprintf("%02d", m.month)
+}
+
+func (m Month) Year() Year {
+	return m.year
 }
 
-func parseMonth(str string) Month {
+func (m Month) inYear(y Year) Period {
+	m.year = y
+	return m
+}
+
+func parseMonth(str string) (Month, error) {
 	month, err := strconv.ParseUint(str, 10, 8)
 	if err != nil || month < 1 || month > 12 {
-		log.Fatalf("Invalid month: %s (%v)", str, err)
+		return Month{}, fmt.Errorf("invalid month '%s'", str)
 	}
-	return Month(month)
+	return Month{month: uint8(month)}, nil
 }
 
 type Months struct {
@@ -35,30 +55,53 @@ type Months struct {
 }
 
 func (m Months) includes(d Date) bool {
-	return d.Month >= int(m.start) && d.Month <= int(m.end)
+	return d.Year == int(m.start.year) && d.Month >= int(m.start.month) && d.Month <= int(m.end.month)
 }
 
 func (m Months) String() string {
-	return fmt.Sprintf("%02d", m.end)
+	return m.end.String()
+}
+
+func (m Months) Year() Year {
+	return m.start.year
 }
 
-type Quarter uint8
+func (m Months) inYear(y Year) Period {
+	m.start.year = y
+	m.end.year = y
+	return m
+}
 
-const (
-	Q1 Quarter = iota + 1
-	Q2
-	Q3
-	Q4
-)
+type Quarter struct {
+	year    Year
+	quarter uint8
+}
 
 func (q Quarter) includes(d Date) bool {
-	end := int(q) * 3
-	return d.Month <= end && d.Month > end-3
+	end := int(q.quarter) * 3
+	return d.Year == int(q.year) && d.Month <= end && d.Month > end-3
 }
 
 func (q Quarter) String() string {
 	// 4x = Qx
-	return fmt.Sprintf("4%d", q)
+	return fmt.Sprintf("4%d", q.quarter)
+}
+
+func (q Quarter) Year() Year {
+	return q.year
+}
+
+func (q Quarter) inYear(y Year) Period {
+	q.year = y
+	return q
+}
+
+func parseQuarter(str string) (Quarter, error) {
+	quarter, err := strconv.ParseUint(str, 10, 8)
+	if err != nil || quarter < 1 || quarter > 4 {
+		return Quarter{}, fmt.Errorf("unknown quarter 'Q%s'", str)
+	}
+	return Quarter{quarter: uint8(quarter)}, nil
 }
 
 type Year uint16
@@ -71,10 +114,183 @@ func (y Year) String() string {
 	return fmt.Sprintf("%d", y)
 }
 
-func parseYear(str string) Year {
+func (y Year) Year() Year {
+	return y
+}
+
+func (y Year) inYear(Year) Period {
+	return y
+}
+
+func parseYear(str string) (Year, error) {
 	year, err := strconv.ParseUint(str, 10, 16)
-	if err != nil {
-		log.Fatalf("Invalid year: %s (%v)", str, err)
+	if err != nil || len(str) != 4 {
+		return 0, fmt.Errorf("invalid year '%s'", str)
+	}
+	return Year(year), nil
+}
+
+// Relative period keywords, resolved against `now`.
+const (
+	periodPrev        = "prev"         // the previous month
+	periodPrevQuarter = "prev-quarter" // the previous quarter
+	periodCurrent     = "current"      // the current month
+	periodYTD         = "ytd"          // January up to the current month
+)
+
+// relativePeriod resolves a relative period keyword. The result is always bound to a year.
+func relativePeriod(keyword string, t time.Time) (Period, bool) {
+	year, month := Year(t.Year()), uint8(t.Month())
+
+	switch keyword {
+	case periodPrev:
+		if month == 1 {
+			return Month{year - 1, 12}, true
+		}
+		return Month{year, month - 1}, true
+	case periodPrevQuarter:
+		quarter := (month-1)/3 + 1
+		if quarter == 1 {
+			return Quarter{year - 1, 4}, true
+		}
+		return Quarter{year, quarter - 1}, true
+	case periodCurrent:
+		return Month{year, month}, true
+	case periodYTD:
+		if month == 1 {
+			return Month{year, 1}, true
+		}
+		return Months{Month{year, 1}, Month{year, month}}, true
+	default:
+		return nil, false
+	}
+}
+
+// parsePeriod parses the period as given on the commandline.
+// The period is not yet bound to a year, if none is explicitly given.
+func parsePeriod(periodStr string) (Period, error) {
+	if period, ok := relativePeriod(strings.ToLower(periodStr), now()); ok {
+		return period, nil
+	}
+
+	if yearStr, quarterStr, found := strings.Cut(strings.ToUpper(periodStr), "Q"); found { // Quarter
+		quarter, err := parseQuarter(quarterStr)
+		if err != nil {
+			return nil, err
+		}
+		if yearStr != "" {
+			if quarter.year, err = parseYear(yearStr); err != nil {
+				return nil, err
+			}
+		}
+		return quarter, nil
+	}
+
+	if startStr, endStr, found := strings.Cut(periodStr, "-"); found {
+		if len(startStr) == 4 { // month in a specific year
+			month, err := parseMonth(endStr)
+			if err != nil {
+				return nil, err
+			}
+			if month.year, err = parseYear(startStr); err != nil {
+				return nil, err
+			}
+			return month, nil
+		}
+
+		// Month range
+		start, err := parseMonth(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseMonth(endStr)
+		if err != nil {
+			return nil, err
+		}
+		if end.month <= start.month {
+			return nil, fmt.Errorf("end month must be larger than starting month in '%s'", periodStr)
+		}
+		return Months{start, end}, nil
+	}
+
+	switch {
+	case len(periodStr) <= 2: // single month
+		return parseMonth(periodStr)
+	case len(periodStr) == 4: // year
+		return parseYear(periodStr)
+	default:
+		return nil, fmt.Errorf("unknown period '%s'", periodStr)
+	}
+}
+
+// isFinalPeriod returns whether the period is the last UStVA period of its year.
+func isFinalPeriod(p Period) bool {
+	switch p := p.(type) {
+	case Month:
+		return p.month == 12
+	case Months:
+		return p.end.month == 12
+	case Quarter:
+		return p.quarter == 4
+	default:
+		return false
+	}
+}
+
+// periodLabel returns a human readable name of the period, including its year.
+func periodLabel(p Period) string {
+	switch p := p.(type) {
+	case Month:
+		return fmt.Sprintf("%d-%02d", p.year, p.month)
+	case Months:
+		return fmt.Sprintf("%d-%02d..%02d", p.start.year, p.start.month, p.end.month)
+	case Quarter:
+		return fmt.Sprintf("%dQ%d", p.year, p.quarter)
+	default:
+		return p.String()
+	}
+}
+
+// periodsOfYear returns all UStVA periods of the year for the given filing frequency. Monthly is the default.
+func periodsOfYear(year Year, filing string) []Period {
+	var periods []Period
+	if filing == filingQuarterly {
+		for q := uint8(1); q <= 4; q++ {
+			periods = append(periods, Quarter{year, q})
+		}
+	} else {
+		for m := uint8(1); m <= 12; m++ {
+			periods = append(periods, Month{year, m})
+		}
+	}
+	return periods
+}
+
+// periodEnd returns the last day of the period.
+func periodEnd(p Period) Date {
+	var month int
+	switch p := p.(type) {
+	case Month:
+		month = int(p.month)
+	case Months:
+		month = int(p.end.month)
+	case Quarter:
+		month = int(p.quarter) * 3
+	default:
+		month = 12
+	}
+
+	// day 0 of the next month is the last day of this month
+	t := time.Date(int(p.Year()), time.Month(month+1), 0, 0, 0, 0, 0, time.UTC)
+	return Date{t.Year(), int(t.Month()), t.Day()}
+}
+
+// periodOf returns the UStVA period including the date for the given filing frequency.
+func periodOf(d Date, filing string) Period {
+	for _, p := range periodsOfYear(Year(d.Year), filing) {
+		if p.includes(d) {
+			return p
+		}
 	}
-	return Year(year)
+	return nil
 }
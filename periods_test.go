@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodIncludes(t *testing.T) {
+	tests := []struct {
+		period Period
+		date   Date
+		want   bool
+	}{
+		{Month{2024, 3}, Date{2024, 3, 15}, true},
+		{Month{2024, 3}, Date{2025, 3, 15}, false},
+		{Month{2024, 3}, Date{2024, 4, 1}, false},
+		{Months{Month{2024, 3}, Month{2024, 5}}, Date{2024, 4, 1}, true},
+		{Months{Month{2024, 3}, Month{2024, 5}}, Date{2023, 4, 1}, false},
+		{Quarter{2024, 4}, Date{2024, 12, 31}, true},
+		{Quarter{2024, 4}, Date{2025, 12, 31}, false},
+		{Quarter{2024, 4}, Date{2024, 9, 30}, false},
+		{Year(2024), Date{2024, 1, 1}, true},
+		{Year(2024), Date{2025, 1, 1}, false},
+	}
+
+	for _, tt := range tests {
+		got := tt.period.includes(tt.date)
+		if got != tt.want {
+			t.Errorf("%#v.includes(%v) = %v, want %v", tt.period, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestInYear(t *testing.T) {
+	tests := []struct {
+		period Period
+		want   Period
+	}{
+		{Month{month: 3}, Month{2024, 3}},
+		{Months{Month{month: 3}, Month{month: 5}}, Months{Month{2024, 3}, Month{2024, 5}}},
+		{Quarter{quarter: 2}, Quarter{2024, 2}},
+		{Year(2024), Year(2024)},
+	}
+
+	for _, tt := range tests {
+		got := tt.period.inYear(2024)
+		if got != tt.want {
+			t.Errorf("%#v.inYear(2024) = %#v, want %#v", tt.period, got, tt.want)
+		}
+		if got.Year() != 2024 {
+			t.Errorf("%#v.Year() = %d, want 2024", got, got.Year())
+		}
+	}
+}
+
+func TestIsFinalPeriod(t *testing.T) {
+	tests := []struct {
+		filing string
+		period Period
+		want   bool
+	}{
+		{"", Month{2024, 12}, true},
+		{"", Month{2024, 3}, false},
+		{"", Quarter{2024, 4}, true},
+		{"", Months{Month{2024, 10}, Month{2024, 12}}, true},
+		{filingMonthly, Month{2024, 12}, true},
+		{filingMonthly, Quarter{2024, 4}, false},
+		{filingQuarterly, Quarter{2024, 4}, true},
+		{filingQuarterly, Quarter{2024, 3}, false},
+		{filingQuarterly, Month{2024, 12}, false},
+		{"", Year(2024), false},
+	}
+
+	for _, tt := range tests {
+		conf := &Config{Filing: tt.filing}
+		if got := conf.isFinalPeriod(tt.period); got != tt.want {
+			t.Errorf("isFinalPeriod(%q, %#v) = %v, want %v", tt.filing, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		str  string
+		want Period
+	}{
+		{"3", Month{month: 3}},
+		{"12", Month{month: 12}},
+		{"Q2", Quarter{quarter: 2}},
+		{"q2", Quarter{quarter: 2}},
+		{"2024Q3", Quarter{2024, 3}},
+		{"2024-07", Month{2024, 7}},
+		{"3-5", Months{Month{month: 3}, Month{month: 5}}},
+		{"2024", Year(2024)},
+		{"prev", Month{2024, 12}},
+		{"prev-quarter", Quarter{2024, 4}},
+		{"current", Month{2025, 1}},
+		{"ytd", Month{2025, 1}},
+		{"PREV", Month{2024, 12}},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePeriod(tt.str)
+		if err != nil {
+			t.Errorf("parsePeriod(%q) failed: %v", tt.str, err)
+		} else if got != tt.want {
+			t.Errorf("parsePeriod(%q) = %#v, want %#v", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestParsePeriodErrors(t *testing.T) {
+	for _, str := range []string{"", "0", "13", "Q5", "Q", "2024Q0", "5-3", "5-5", "2024-13", "abc", "24Q1", "12345", "prevquarter"} {
+		if got, err := parsePeriod(str); err == nil {
+			t.Errorf("parsePeriod(%q) = %#v, want error", str, got)
+		}
+	}
+}
+
+func TestRelativePeriod(t *testing.T) {
+	tests := []struct {
+		keyword string
+		now     time.Time
+		want    Period
+	}{
+		{periodPrev, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), Month{2024, 7}},
+		{periodPrev, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Month{2023, 12}},
+		{periodPrevQuarter, time.Date(2024, 10, 5, 0, 0, 0, 0, time.UTC), Quarter{2024, 3}},
+		{periodPrevQuarter, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), Quarter{2023, 4}},
+		{periodPrevQuarter, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Quarter{2024, 1}},
+		{periodCurrent, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), Month{2024, 8}},
+		{periodYTD, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), Months{Month{2024, 1}, Month{2024, 8}}},
+	}
+
+	for _, tt := range tests {
+		got, ok := relativePeriod(tt.keyword, tt.now)
+		if !ok || got != tt.want {
+			t.Errorf("relativePeriod(%s, %s) = %#v, want %#v", tt.keyword, tt.now.Format(dateLayout), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+)
+
+// privateUseAccounts are the tax accounts private use can be reported on: 19% (Kz 81) and 7% (Kz 86).
+var privateUseAccounts = []TaxAccount{500, 510}
+
+// PrivateUseRule marks booking accounts for unentgeltliche Wertabgaben, i.e. the private use of business assets.
+// It is taxable turnover and reported in Kz 81 or 86, but listed separately.
+type PrivateUseRule struct {
+	// Booking accounts the rule applies to
+	BookingAccounts []int `json:"bookingAccounts"`
+	// Optional: tax account for payments booked without one, 500 (19%) by default or 510 (7%)
+	Account TaxAccount `json:"account"`
+}
+
+// account returns the tax account for payments booked without one.
+func (r PrivateUseRule) account() TaxAccount {
+	return cmp.Or(r.Account, privateUseAccounts[0])
+}
+
+// preparePrivateUse checks the rules for private use.
+func (e *Eur) preparePrivateUse(rules []PrivateUseRule) {
+	for _, r := range rules {
+		if len(r.BookingAccounts) == 0 {
+			log.Fatalf("Rule for private use needs booking accounts.")
+		}
+		if !slices.Contains(privateUseAccounts, r.account()) {
+			log.Fatalf("Invalid tax account %d for private use, expected one of %v.", r.Account, privateUseAccounts)
+		}
+	}
+
+	e.privateUse = rules
+}
+
+// privateUseRule returns the private use rule matching the payment, if any.
+func (e *Eur) privateUseRule(p *Payment) (PrivateUseRule, bool) {
+	for _, r := range e.privateUse {
+		if slices.Contains(r.BookingAccounts, p.Account) {
+			return r, true
+		}
+	}
+	return PrivateUseRule{}, false
+}
+
+// isPrivateUse returns whether the payment on the given tax account is private use.
+func (e *Eur) isPrivateUse(p *Payment, acc TaxAccount) bool {
+	_, ok := e.privateUseRule(p)
+	return ok && slices.Contains(privateUseAccounts, acc)
+}
+
+// WritePrivateUse lists the share of private use in each Kennzahl.
+func WritePrivateUse(w io.Writer, vatData VatData) {
+	for _, m := range mappings {
+		if m.category != PrivateUse {
+			continue
+		}
+		if vd := vatData[VatKey{m.account, PrivateUse}]; !vd.Empty() {
+			fmt.Fprintf(w, "    Kz %02d: thereof private use %s (tax %s)\n", m.kz, vd.NetAmount, vd.Tax)
+		}
+	}
+}
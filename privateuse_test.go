@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivateUse(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		500: {Number: 500, Percent: 19 * pct},
+		510: {Number: 510, Percent: 7 * pct},
+	}}
+	e.preparePrivateUse([]PrivateUseRule{
+		{BookingAccounts: []int{8921}},
+		{BookingAccounts: []int{8915}, Account: 510},
+	})
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "1000")}},
+		// private use of the car, booked on the tax account
+		{Number: 2, Date: Date{2024, 1, 31}, Paid: true, Payments: []*Payment{newPayment(500, 8921, "200")}},
+		// private use booked without tax account
+		{Number: 3, Date: Date{2024, 1, 31}, Paid: true, Payments: []*Payment{newPayment(0, 8921, "50")}},
+		{Number: 4, Date: Date{2024, 1, 31}, Paid: true, Payments: []*Payment{newPayment(0, 8915, "100")}},
+		{Number: 5, Date: Date{2024, 1, 31}, Paid: true, Payments: []*Payment{newPayment(510, 8300, "300")}},
+	}
+	e.Validate()
+
+	vatData := e.VatData(Month{2024, 1})
+	tests := []struct {
+		key VatKey
+		net Cents
+	}{
+		{VatKey{500, Regular}, 100000},
+		{VatKey{500, PrivateUse}, 25000},
+		{VatKey{510, Regular}, 30000},
+		{VatKey{510, PrivateUse}, 10000},
+	}
+	for _, tt := range tests {
+		if got := vatData[tt.key].NetAmount; got != tt.net {
+			t.Errorf("VatData[%v] = %s, want %s", tt.key, got, tt.net)
+		}
+	}
+
+	k := kennzahlenFromVatData(vatData)
+	if got := k[81].amountString(); got != "1250" {
+		t.Errorf("Kz 81 = %s, want 1250", got)
+	}
+	if got := k[86].amountString(); got != "400" {
+		t.Errorf("Kz 86 = %s, want 400", got)
+	}
+
+	var sb strings.Builder
+	WritePrivateUse(&sb, vatData)
+	want := "    Kz 81: thereof private use 250.00 EUR (tax 47.50 EUR)\n" +
+		"    Kz 86: thereof private use 100.00 EUR (tax 7.00 EUR)\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WritePrivateUse() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"slices"
+)
+
+// RateChange defines a tax rate for a tax account that deviates from the account's rate in JES
+// for receipts dated within [From, Until].
+type RateChange struct {
+	Account TaxAccount `json:"account"`
+	From    Date       `json:"from"`
+	Until   Date       `json:"until"` // open-ended if not given
+	Percent Rate       `json:"percent"`
+	// Optional: only applies to payments on these booking accounts
+	BookingAccounts []int `json:"bookingAccounts"`
+}
+
+// builtinRates holds the statutory rate changes. Rate changes from the config take precedence.
+var builtinRates = []RateChange{
+	// Temporary reduction (Zweites Corona-Steuerhilfegesetz): 19% -> 16%, 7% -> 5%
+	{Account: 500, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 510, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 5 * pct},
+	{Account: 100, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 110, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 5 * pct},
+	{Account: 600, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 200, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 650, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 655, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 5 * pct},
+	{Account: 250, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 16 * pct},
+	{Account: 255, From: Date{2020, 7, 1}, Until: Date{2020, 12, 31}, Percent: 5 * pct},
+}
+
+// rateSiblings groups tax accounts that only differ in their tax rate.
+var rateSiblings = [][]TaxAccount{
+	{500, 510},
+	{100, 110},
+	{650, 655},
+	{250, 255},
+}
+
+// sibling returns the tax account that regularly has the given rate and otherwise is equivalent to `acc`.
+func (e *Eur) sibling(acc TaxAccount, percent Rate) (TaxAccount, bool) {
+	for _, group := range rateSiblings {
+		if !slices.Contains(group, acc) {
+			continue
+		}
+		for _, other := range group {
+			if info, ok := e.accountInfo[other]; ok && other != acc && info.Percent == percent {
+				return other, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (r RateChange) applies(acc TaxAccount, bookingAccount int, date Date) bool {
+	if r.Account != acc || date.compare(r.From) < 0 {
+		return false
+	}
+	if !r.Until.IsZero() && date.compare(r.Until) > 0 {
+		return false
+	}
+	return len(r.BookingAccounts) == 0 || slices.Contains(r.BookingAccounts, bookingAccount)
+}
+
+// prepareRates sets up the rate timeline from the config and the builtin rates.
+func (e *Eur) prepareRates(configured []RateChange) {
+	e.rates = slices.Concat(configured, builtinRates)
+}
+
+// rate returns the tax rate for the payment on the given tax account, depending on the receipt date.
+// The second return value reports whether the rate deviates from the account's rate in JES.
+func (e *Eur) rate(p *Payment, acc TaxAccount) (Rate, bool) {
+	percent := e.accountInfo[acc].Percent
+
+	for _, r := range e.rates {
+		if r.applies(acc, p.Account, p.receipt.Date) {
+			return r.Percent, r.Percent != percent
+		}
+	}
+
+	return percent, false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRate(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		500: {Number: 500, Percent: 19 * pct},
+		510: {Number: 510, Percent: 7 * pct},
+	}}
+	e.prepareRates([]RateChange{
+		{Account: 510, From: Date{2024, 1, 1}, Percent: 19 * pct, BookingAccounts: []int{4711}},
+	})
+
+	tests := []struct {
+		acc           TaxAccount
+		account       int
+		date          Date
+		want          Rate
+		wantDeviating bool
+	}{
+		{500, 1, Date{2020, 6, 30}, 19 * pct, false},
+		{500, 1, Date{2020, 7, 1}, 16 * pct, true},
+		{500, 1, Date{2020, 12, 31}, 16 * pct, true},
+		{500, 1, Date{2021, 1, 1}, 19 * pct, false},
+		{510, 1, Date{2020, 8, 1}, 5 * pct, true},
+		{510, 1, Date{2024, 3, 1}, 7 * pct, false},
+		{510, 4711, Date{2024, 3, 1}, 19 * pct, true},
+		{510, 4711, Date{2023, 12, 31}, 7 * pct, false},
+	}
+
+	for _, tt := range tests {
+		p := &Payment{Account: tt.account, receipt: &Receipt{Date: tt.date}}
+		got, deviating := e.rate(p, tt.acc)
+		if got != tt.want || deviating != tt.wantDeviating {
+			t.Errorf("rate(Kto %d/%d, %v) = %s, %v; want %s, %v",
+				tt.acc, tt.account, tt.date, got, deviating, tt.want, tt.wantDeviating)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		500: {Number: 500, Percent: 19 * pct},
+		510: {Number: 510, Percent: 7 * pct},
+		100: {Number: 100, Percent: 19 * pct},
+	}}
+	e.prepareRates([]RateChange{
+		{Account: 510, From: Date{2024, 1, 1}, Percent: 19 * pct, BookingAccounts: []int{4711}},
+	})
+
+	tests := []struct {
+		acc     TaxAccount
+		account int
+		date    Date
+		want    VatKey
+		percent Rate
+	}{
+		{500, 1, Date{2020, 6, 30}, VatKey{500, Regular}, 19 * pct},
+		{500, 1, Date{2020, 7, 1}, VatKey{500, OtherRate}, 16 * pct},
+		{510, 4711, Date{2024, 3, 1}, VatKey{500, Regular}, 19 * pct},
+		{100, 1, Date{2020, 7, 1}, VatKey{100, Regular}, 16 * pct},
+	}
+
+	for _, tt := range tests {
+		p := &Payment{Account: tt.account, receipt: &Receipt{Date: tt.date}}
+		got, percent := e.classify(p, tt.acc)
+		if got != tt.want || percent != tt.percent {
+			t.Errorf("classify(Kto %d/%d, %v) = %v, %s; want %v, %s",
+				tt.acc, tt.account, tt.date, got, percent, tt.want, tt.percent)
+		}
+	}
+}
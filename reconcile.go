@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// Status of a period in the reconciliation of tax payments.
+const (
+	statusOK          = "OK"
+	statusNone        = "-"
+	statusMissing     = "Zahlung fehlt"
+	statusNoRefund    = "Erstattung fehlt"
+	statusDuplicate   = "Doppelzahlung"
+	statusUnallocated = "nicht zugeordnet"
+)
+
+// ReconcileEntry is an expected payment to or refund from the Finanzamt, together with the booking matched to it.
+type ReconcileEntry struct {
+	Label     string
+	After     Date  // earliest date of the booking
+	Zahllast  Cents // positive for payments, negative for refunds
+	Booking   *TaxBooking
+	Duplicate []TaxBooking
+}
+
+func (r *ReconcileEntry) Status() string {
+	switch {
+	case len(r.Duplicate) > 0:
+		return statusDuplicate
+	case r.Booking != nil:
+		return statusOK
+	case r.Zahllast > 0:
+		return statusMissing
+	case r.Zahllast < 0:
+		return statusNoRefund
+	default:
+		return statusNone
+	}
+}
+
+// allTaxBookings collects the tax bookings of all business years of the JES files, ordered by date.
+func allTaxBookings(jes []*Eur) []TaxBooking {
+	var bookings []TaxBooking
+	for _, e := range jes {
+		for y := e.Start.Year; y <= e.End.Year; y++ {
+			bookings = append(bookings, e.taxBookings(Year(y))...)
+		}
+	}
+	slices.SortStableFunc(bookings, func(a, b TaxBooking) int {
+		return a.Date.compare(b.Date)
+	})
+	return bookings
+}
+
+// reconcile matches the bookings to the expected payments, which are required to be ordered by date.
+// A booking matches, if it has the exact amount and is booked after the end of the period.
+// Bookings not matching any expected payment are returned.
+func reconcile(entries []*ReconcileEntry, bookings []TaxBooking) []TaxBooking {
+	assigned := make([]bool, len(bookings))
+
+	for _, e := range entries {
+		if e.Zahllast == 0 {
+			continue
+		}
+		for i, b := range bookings {
+			if !assigned[i] && b.Amount == e.Zahllast && b.Date.compare(e.After) > 0 {
+				assigned[i] = true
+				e.Booking = &bookings[i]
+				break
+			}
+		}
+	}
+
+	// remaining bookings with the amount of an already paid period are considered duplicates,
+	// assigned to the latest such period
+	var unallocated []TaxBooking
+bookingLoop:
+	for i, b := range bookings {
+		if assigned[i] {
+			continue
+		}
+		for _, e := range slices.Backward(entries) {
+			if e.Booking != nil && e.Zahllast == b.Amount && b.Date.compare(e.After) > 0 {
+				e.Duplicate = append(e.Duplicate, b)
+				continue bookingLoop
+			}
+		}
+		unallocated = append(unallocated, b)
+	}
+
+	return unallocated
+}
+
+// reconcileEntries computes the expected payments of the year: the Zahllast of each UStVA period and,
+// if applicable, the Sondervorauszahlung.
+func reconcileEntries(conf *Config, jes []*Eur, year Year) []*ReconcileEntry {
+	var entries []*ReconcileEntry
+
+	svz, _ := knownSvz(conf, int(year))
+	if svz != 0 {
+		entries = append(entries, &ReconcileEntry{
+			Label:    dfvLabel(year),
+			After:    Date{int(year) - 1, 12, 31},
+			Zahllast: svz,
+		})
+	}
+
+	for _, period := range periodsOfYear(year, conf.Filing) {
+		zahllast := periodKennzahlen(conf, jes, period).TaxSum(conf.TaxComputation)
+		if conf.isFinalPeriod(period) {
+			zahllast -= svz
+		}
+
+		entries = append(entries, &ReconcileEntry{
+			Label:    periodLabel(period),
+			After:    periodEnd(period),
+			Zahllast: zahllast,
+		})
+	}
+
+	return entries
+}
+
+// WriteReconciliation prints the table of expected payments and their bookings.
+func WriteReconciliation(w io.Writer, entries []*ReconcileEntry, unallocated []TaxBooking) {
+	format := "%6d,%02d EUR"
+
+	fmt.Fprintf(w, "Zeitraum\tZahllast\t\tGebucht\t\t\tDatum\t\tBeleg\tStatus\n")
+	for _, e := range entries {
+		booked, date, receipt := "-\t", "-\t", "-"
+		if e.Booking != nil {
+			booked = e.Booking.Amount.Format(format)
+			date = e.Booking.Date.String()
+			receipt = "#" + strconv.Itoa(e.Booking.Receipt)
+		}
+		fmt.Fprintf(w, "%s\t\t%s\t%s\t%s\t%s\t%s\n", e.Label, e.Zahllast.Format(format), booked, date, receipt, e.Status())
+
+		for _, d := range e.Duplicate {
+			fmt.Fprintf(w, "\t\t\t\t\t%s\t%s\t#%d\t%s\n", d.Amount.Format(format), d.Date, d.Receipt, statusDuplicate)
+		}
+	}
+
+	for _, b := range unallocated {
+		fmt.Fprintf(w, "-\t\t\t\t\t%s\t%s\t#%d\t%s\n", b.Amount.Format(format), b.Date, b.Receipt, statusUnallocated)
+	}
+}
+
+// cmdReconcile handles
+//
+//	reconcile <jes.file> [<jes.file>...] <year>
+//
+// Bookings are taken from all given JES files, so the file of the following year may be passed as well.
+func cmdReconcile(conf *Config, args []string) {
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jesFiles, args := splitJesArgs(args)
+	year := yearArg(args[0])
+	jes := loadJes(conf, jesFiles)
+
+	entries := reconcileEntries(conf, jes, year)
+	unallocated := reconcile(entries, allTaxBookings(jes))
+	WriteReconciliation(os.Stdout, entries, unallocated)
+}
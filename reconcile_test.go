@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	entries := []*ReconcileEntry{
+		{Label: "2024-01", After: Date{2024, 1, 31}, Zahllast: 10000},
+		{Label: "2024-02", After: Date{2024, 2, 29}, Zahllast: 10000},
+		{Label: "2024-03", After: Date{2024, 3, 31}, Zahllast: 5000},
+		{Label: "2024-04", After: Date{2024, 4, 30}, Zahllast: -2000},
+		{Label: "2024-05", After: Date{2024, 5, 31}, Zahllast: 0},
+		{Label: "2024-06", After: Date{2024, 6, 30}, Zahllast: -3000},
+	}
+	bookings := []TaxBooking{
+		{Date{2024, 1, 10}, 20000, 1}, // previous year
+		{Date{2024, 2, 10}, 10000, 2},
+		{Date{2024, 3, 8}, 10000, 3},
+		{Date{2024, 3, 9}, 10000, 4},
+		{Date{2024, 5, 20}, -2000, 6},
+	}
+
+	unallocated := reconcile(entries, bookings)
+
+	wantStatus := []string{statusOK, statusDuplicate, statusMissing, statusOK, statusNone, statusNoRefund}
+	for i, e := range entries {
+		if got := e.Status(); got != wantStatus[i] {
+			t.Errorf("Status of %s = %q, want %q", e.Label, got, wantStatus[i])
+		}
+	}
+
+	if b := entries[0].Booking; b == nil || b.Receipt != 2 {
+		t.Errorf("Booking of 2024-01 = %v, want #2", b)
+	}
+	if b := entries[1].Booking; b == nil || b.Receipt != 3 {
+		t.Errorf("Booking of 2024-02 = %v, want #3", b)
+	}
+
+	if !slices.Equal(unallocated, bookings[:1]) {
+		t.Errorf("Unallocated bookings = %v, want %v", unallocated, bookings[:1])
+	}
+}
+
+func TestAllTaxBookings(t *testing.T) {
+	newEur := func(year int, dates ...Date) *Eur {
+		e := &Eur{Start: Date{year, 1, 1}, End: Date{year, 12, 31}, taxBookingAccounts: map[int]struct{}{1780: {}}}
+		for i, d := range dates {
+			e.Receipts = append(e.Receipts, &Receipt{Number: i + 1, Date: d, Paid: true,
+				Payments: []*Payment{newPayment(0, 1780, "100")}})
+		}
+		return e
+	}
+
+	// the file of the following year is given first, with the payment of December
+	jes := []*Eur{
+		newEur(2025, Date{2025, 1, 10}),
+		newEur(2024, Date{2024, 12, 10}, Date{2024, 2, 10}),
+	}
+
+	var got []Date
+	for _, b := range allTaxBookings(jes) {
+		got = append(got, b.Date)
+	}
+	want := []Date{{2024, 2, 10}, {2024, 12, 10}, {2025, 1, 10}}
+	if !slices.Equal(got, want) {
+		t.Errorf("allTaxBookings() = %v, want %v", got, want)
+	}
+}
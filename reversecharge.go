@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// reverseChargeAccount is the tax account for taxes owed under §13b UStG.
+const reverseChargeAccount TaxAccount = 600
+
+// ReverseChargeRule assigns payments on the §13b tax account to a Kennzahl pair other than the default Kz 46/47.
+// The matching Vorsteuer is still reported in Kz 67.
+type ReverseChargeRule struct {
+	// Base Kennzahl of the pair, i.e. 46, 52, 73, 78 or 84
+	Kz int `json:"kz"`
+	// Booking accounts the rule applies to
+	BookingAccounts []int `json:"bookingAccounts"`
+}
+
+// reverseChargeKz maps the base Kennzahlen of the §13b pairs to their Category.
+var reverseChargeKz = map[int]Category{
+	46: Regular,
+	52: ReverseCharge52,
+	73: ReverseCharge73,
+	78: ReverseCharge78,
+	84: ReverseCharge84,
+}
+
+// prepareReverseCharge checks the rules for §13b services.
+func (e *Eur) prepareReverseCharge(rules []ReverseChargeRule) {
+	for _, r := range rules {
+		if _, ok := reverseChargeKz[r.Kz]; !ok {
+			log.Fatalf("Invalid Kennzahl %d for §13b UStG, expected one of 46, 52, 73, 78 or 84.", r.Kz)
+		}
+		if len(r.BookingAccounts) == 0 {
+			log.Fatalf("Rule for §13b Kz %d needs booking accounts.", r.Kz)
+		}
+	}
+
+	e.reverseCharge = rules
+}
+
+// reverseChargeCategory returns the category of the payment on the given tax account.
+// Payments not matching any rule are `Regular`, i.e. Kz 46/47.
+func (e *Eur) reverseChargeCategory(p *Payment, acc TaxAccount) Category {
+	if acc != reverseChargeAccount {
+		return Regular
+	}
+
+	for _, r := range e.reverseCharge {
+		if slices.Contains(r.BookingAccounts, p.Account) {
+			return reverseChargeKz[r.Kz]
+		}
+	}
+	return Regular
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestReverseCharge(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{
+		600: {Number: 600, Percent: 19 * pct},
+		200: {Number: 200, Percent: 19 * pct},
+	}}
+	e.prepareReverseCharge([]ReverseChargeRule{
+		{Kz: 84, BookingAccounts: []int{4925}},
+		{Kz: 73, BookingAccounts: []int{4926}},
+	})
+
+	rc := func(account int, value string) *Payment {
+		p := newPayment(600, account, value)
+		p.Outgoing = 200
+		return p
+	}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{rc(4900, "100")}},
+		{Number: 2, Date: Date{2024, 1, 11}, Paid: true, Payments: []*Payment{rc(4925, "200")}},
+		{Number: 3, Date: Date{2024, 1, 12}, Paid: true, Payments: []*Payment{rc(4926, "300")}},
+	}
+	e.Validate()
+
+	k := kennzahlenFromVatData(e.VatData(Month{2024, 1}))
+
+	want := map[int]string{
+		46: "100", 47: "19.00",
+		84: "200", 85: "38.00",
+		73: "300", 74: "57.00",
+		67: "114.00",
+	}
+	for id, amount := range want {
+		if kz, ok := k[id]; !ok || kz.amountString() != amount {
+			t.Errorf("Kz %d = %v, want %s", id, kz, amount)
+		}
+	}
+	if len(k) != len(want) {
+		t.Errorf("Unexpected Kennzahlen: %v", k)
+	}
+
+	if sum := k.TaxSum(taxPerTotal); sum != 0 {
+		t.Errorf("TaxSum() = %s, want 0", sum)
+	}
+}
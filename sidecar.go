@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sidecarExt is the extension of the sidecar file, which is placed next to the JES file.
+// For `2024.eux` the sidecar is `2024.jesva.json`.
+const sidecarExt = ".jesva.json"
+
+// Sidecar holds additional data on the receipts of a JES file, which cannot be stored in JES itself.
+type Sidecar struct {
+	Receipts map[int]ReceiptInfo `json:"receipts"` // by receipt number
+}
+
+// ReceiptInfo holds additional data of a single receipt.
+type ReceiptInfo struct {
+	VatID   string `json:"vatId"`   // USt-IdNr. of the customer
+	Country string `json:"country"` // country of destination for OSS sales
+	// Number of the receipt corrected by this credit note or cancellation
+	Corrects int `json:"corrects"`
+	// Date the receipt has been written off as uncollectible (§17 UStG)
+	WrittenOff Date `json:"writtenOff"`
+	// Deductible share of the input tax in percent, if only partially deductible
+	Deductible *Rate `json:"deductible"`
+	// Whether the depreciation plan is carried over from an earlier business year, if dated on the first day
+	CarriedOver *bool `json:"carriedOver"`
+}
+
+func sidecarName(jesFile string) string {
+	return strings.TrimSuffix(jesFile, filepath.Ext(jesFile)) + sidecarExt
+}
+
+// readSidecar loads the sidecar file for the given JES file. It is optional.
+func readSidecar(jesFile string) *Sidecar {
+	name := sidecarName(jesFile)
+	sidecar := new(Sidecar)
+
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sidecar
+	}
+	if err != nil {
+		log.Fatalf("Reading sidecar file '%s': %v", name, err)
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.DisallowUnknownFields()
+
+	if err = d.Decode(sidecar); err != nil {
+		log.Fatalf("Parsing sidecar file '%s': %v", name, err)
+	}
+
+	return sidecar
+}
+
+// attachSidecar links the sidecar data to the receipts.
+func (e *Eur) attachSidecar(sidecar *Sidecar) {
+	for _, r := range e.Receipts {
+		r.info = sidecar.Receipts[r.Number]
+	}
+}
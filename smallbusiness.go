@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+)
+
+// SmallBusiness configures the Kleinunternehmerregelung (§19 UStG).
+type SmallBusiness struct {
+	// Booking accounts of turnover booked without tax account, as done by Kleinunternehmer
+	IncomeAccounts []int `json:"incomeAccounts"`
+	// Optional: end of the Kleinunternehmer status. Receipts before this date are not subject to VAT.
+	Until Date `json:"until"`
+}
+
+// smallBusinessLimit holds the turnover limits of §19 UStG, valid from the given year on.
+type smallBusinessLimit struct {
+	from    int
+	prior   Cents // limit for the turnover of the previous year
+	current Cents // limit for the turnover of the current year
+	// Whether crossing the current limit ends the status immediately. Before 2025, the current limit
+	// applied to the expected turnover at the beginning of the year.
+	immediate bool
+}
+
+var smallBusinessLimits = []smallBusinessLimit{
+	{2025, 25_000_00, 100_000_00, true},
+	{2020, 22_000_00, 50_000_00, false},
+	{0, 17_500_00, 50_000_00, false},
+}
+
+func limitsForYear(year int) smallBusinessLimit {
+	i := slices.IndexFunc(smallBusinessLimits, func(l smallBusinessLimit) bool { return year >= l.from })
+	return smallBusinessLimits[i]
+}
+
+// notTurnoverAccounts are income tax accounts holding taxes on purchases, which are not part of the own turnover.
+var notTurnoverAccounts = []TaxAccount{reverseChargeAccount, 650, 655}
+
+// Turnover is the turnover of a single receipt, as relevant for §19 UStG.
+type Turnover struct {
+	Receipt int
+	Date    Date
+	Amount  Cents
+}
+
+// turnovers returns the turnover of each paid receipt of the year in order of date.
+func (e *Eur) turnovers(year int, incomeAccounts []int) []Turnover {
+	var result []Turnover
+
+	for _, r := range e.Receipts {
+		if !r.Paid || r.Date.Year != year || e.carriedOver(r) {
+			continue
+		}
+
+		var amount Cents
+		for _, p := range r.Payments {
+			if p.Incoming == 0 && p.Outgoing == 0 {
+				if slices.Contains(incomeAccounts, p.Account) {
+					amount += p.getValue()
+				}
+				continue
+			}
+
+			for _, acc := range []TaxAccount{p.Incoming, p.Outgoing} {
+				if acc != 0 && acc.IsIncome() && !slices.Contains(notTurnoverAccounts, acc) {
+					_, percent := e.classify(p, acc)
+					amount += p.getNetAmount(percent)
+				}
+			}
+		}
+
+		if amount != 0 {
+			result = append(result, Turnover{r.Number, r.Date, amount})
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b Turnover) int {
+		return a.Date.compare(b.Date)
+	})
+	return result
+}
+
+// SmallBusinessCheck is the result of checking the limits of §19 UStG for a year.
+type SmallBusinessCheck struct {
+	Year          int
+	Limits        smallBusinessLimit
+	PriorTurnover Cents
+	PriorKnown    bool // whether the JES file of the previous year has been given
+	Turnover      Cents
+	Crossing      *Turnover // receipt crossing the current limit, if any
+	CrossingSum   Cents     // turnover including the crossing receipt
+}
+
+// checkSmallBusiness sums up the turnover of the year and the previous one and finds the receipt crossing the limit.
+func checkSmallBusiness(jes []*Eur, year int, incomeAccounts []int) SmallBusinessCheck {
+	check := SmallBusinessCheck{Year: year, Limits: limitsForYear(year)}
+
+	var current []Turnover
+	for _, e := range jes {
+		if e.coversYear(year - 1) {
+			check.PriorKnown = true
+			for _, t := range e.turnovers(year-1, incomeAccounts) {
+				check.PriorTurnover += t.Amount
+			}
+		}
+		current = append(current, e.turnovers(year, incomeAccounts)...)
+	}
+	slices.SortStableFunc(current, func(a, b Turnover) int {
+		return a.Date.compare(b.Date)
+	})
+
+	for _, t := range current {
+		check.Turnover += t.Amount
+		if check.Crossing == nil && check.Turnover > check.Limits.current {
+			check.Crossing = &t
+			check.CrossingSum = check.Turnover
+		}
+	}
+
+	return check
+}
+
+// WriteSmallBusinessCheck prints the turnover, the limits and the remaining headroom.
+func WriteSmallBusinessCheck(w io.Writer, c SmallBusinessCheck) {
+	format := "%8d,%02d EUR"
+
+	if c.PriorKnown {
+		fmt.Fprintf(w, "Umsatz %d:\t%s\tGrenze %s\tSpielraum %s\n", c.Year-1,
+			c.PriorTurnover.Format(format), c.Limits.prior.Format(format), (c.Limits.prior - c.PriorTurnover).Format(format))
+	} else {
+		fmt.Fprintf(w, "Umsatz %d:\tunbekannt (JES-Datei des Vorjahres nicht angegeben)\n", c.Year-1)
+	}
+	fmt.Fprintf(w, "Umsatz %d:\t%s\tGrenze %s\tSpielraum %s\n", c.Year,
+		c.Turnover.Format(format), c.Limits.current.Format(format), (c.Limits.current - c.Turnover).Format(format))
+
+	fmt.Fprintln(w)
+	switch {
+	case c.PriorKnown && c.PriorTurnover > c.Limits.prior:
+		fmt.Fprintf(w, "Die Kleinunternehmerregelung ist %d nicht anwendbar: Vorjahresgrenze überschritten.\n", c.Year)
+	case c.Crossing != nil && c.Limits.immediate:
+		fmt.Fprintf(w, "Grenze überschritten mit Beleg #%d vom %s (Umsatz %s).\n",
+			c.Crossing.Receipt, c.Crossing.Date, c.CrossingSum.Format("%d,%02d EUR"))
+		fmt.Fprintf(w, "Ab diesem Beleg gilt die Regelbesteuerung, siehe `kleinunternehmer.until` in der Konfiguration.\n")
+	case c.Crossing != nil:
+		fmt.Fprintf(w, "Grenze überschritten mit Beleg #%d vom %s. Die Regelbesteuerung gilt ab dem Folgejahr,\n",
+			c.Crossing.Receipt, c.Crossing.Date)
+		fmt.Fprintf(w, "sofern der Umsatz zu Beginn des Jahres auf höchstens %s geschätzt war.\n",
+			c.Limits.current.Format("%d,%02d EUR"))
+	default:
+		fmt.Fprintf(w, "Die Grenzen sind eingehalten.\n")
+	}
+}
+
+// beforeSwitch returns whether the receipt is dated before the end of the Kleinunternehmer status,
+// i.e. not subject to VAT.
+func (e *Eur) beforeSwitch(r *Receipt) bool {
+	return !e.smallBusinessUntil.IsZero() && r.Date.compare(e.smallBusinessUntil) < 0
+}
+
+// cmdSmallBusiness handles
+//
+//	kleinunternehmer <jes.file> [<jes.file>...] <year>
+//
+// Pass the JES file of the previous year as well, to check the limit of the previous year.
+func cmdSmallBusiness(conf *Config, args []string) {
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jesFiles, args := splitJesArgs(args)
+	year := yearArg(args[0])
+	jes := loadJes(conf, jesFiles)
+
+	var incomeAccounts []int
+	if conf.SmallBusiness != nil {
+		incomeAccounts = conf.SmallBusiness.IncomeAccounts
+	}
+
+	check := checkSmallBusiness(jes, int(year), incomeAccounts)
+	WriteSmallBusinessCheck(os.Stdout, check)
+}
+
+// smallBusinessUntil returns the configured end of the Kleinunternehmer status, if any.
+func (c *Config) smallBusinessUntil() Date {
+	if c.SmallBusiness == nil {
+		return Date{}
+	}
+	return c.SmallBusiness.Until
+}
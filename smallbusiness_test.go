@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckSmallBusiness(t *testing.T) {
+	prior := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}}
+	prior.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 5, 1}, Paid: true, Payments: []*Payment{newPayment(0, 8195, "20000")}},
+		// expenses do not count
+		{Number: 2, Date: Date{2024, 6, 1}, Paid: true, Payments: []*Payment{newPayment(0, 4930, "3000")}},
+	}
+	prior.Validate()
+
+	current := &Eur{
+		Start:       Date{2025, 1, 1},
+		End:         Date{2025, 12, 31},
+		accountInfo: map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}, 600: {Number: 600, Percent: 19 * pct}},
+	}
+	current.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2025, 3, 1}, Paid: true, Payments: []*Payment{newPayment(0, 8195, "60000")}},
+		// unpaid receipts do not count
+		{Number: 2, Date: Date{2025, 4, 1}, Payments: []*Payment{newPayment(0, 8195, "10000")}},
+		// §13b services received are no turnover
+		{Number: 3, Date: Date{2025, 5, 1}, Paid: true, Payments: []*Payment{newPayment(600, 4930, "5000")}},
+		{Number: 5, Date: Date{2025, 9, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "40000")}},
+		{Number: 4, Date: Date{2025, 7, 1}, Paid: true, Payments: []*Payment{newPayment(0, 8195, "30000")}},
+	}
+	current.Validate()
+
+	c := checkSmallBusiness([]*Eur{prior, current}, 2025, []int{8195})
+	if !c.PriorKnown || c.PriorTurnover != 20_000_00 {
+		t.Errorf("prior turnover = %s (known: %v), want 20000.00 EUR", c.PriorTurnover, c.PriorKnown)
+	}
+	if c.Turnover != 130_000_00 {
+		t.Errorf("turnover = %s, want 130000.00 EUR", c.Turnover)
+	}
+	if c.Crossing == nil || c.Crossing.Receipt != 5 || c.CrossingSum != 130_000_00 {
+		t.Errorf("crossing = %+v (%s), want receipt #5", c.Crossing, c.CrossingSum)
+	}
+	if !c.Limits.immediate || c.Limits.current != 100_000_00 || c.Limits.prior != 25_000_00 {
+		t.Errorf("limits = %+v, want the ones of 2025", c.Limits)
+	}
+
+	c = checkSmallBusiness([]*Eur{current}, 2025, []int{8195})
+	if c.PriorKnown {
+		t.Errorf("prior turnover known without JES file of 2024")
+	}
+}
+
+func TestLimitsForYear(t *testing.T) {
+	tests := []struct {
+		year int
+		want Cents
+	}{
+		{2019, 17_500_00},
+		{2020, 22_000_00},
+		{2024, 22_000_00},
+		{2025, 25_000_00},
+		{2030, 25_000_00},
+	}
+
+	for _, tt := range tests {
+		if got := limitsForYear(tt.year).prior; got != tt.want {
+			t.Errorf("limitsForYear(%d).prior = %s, want %s", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestSmallBusinessSwitch(t *testing.T) {
+	e := &Eur{
+		accountInfo:        map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+		smallBusinessUntil: Date{2025, 9, 1},
+	}
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2025, 8, 31}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100")}},
+		{Number: 2, Date: Date{2025, 9, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "200")}},
+	}
+	e.Validate()
+
+	if got := e.VatData(Year(2025))[VatKey{500, Regular}].NetAmount; got != 200_00 {
+		t.Errorf("turnover after switch = %s, want 200.00 EUR", got)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// TaxFreeRule classifies payments on a tax-free tax account into the Kennzahl they are reported in.
+type TaxFreeRule struct {
+	Account TaxAccount `json:"account"`
+	Kz      int        `json:"kz"`
+	// Optional: only applies to payments on these booking accounts
+	BookingAccounts []int `json:"bookingAccounts"`
+}
+
+// taxFreeKz maps the Kennzahlen for tax-free and non-taxable turnover to their Category.
+var taxFreeKz = map[int]Category{
+	21: EUService,
+	41: IntraCommunityDelivery,
+	42: Triangular,
+	43: Export,
+	45: NotTaxable,
+	48: TaxFreeNoDeduction,
+}
+
+// taxFreeAccount is the default tax account for tax-free turnover.
+// Further tax-free accounts can be given in the rules, they are reported like this one.
+const taxFreeAccount TaxAccount = 520
+
+// isTaxFreeAccount returns whether the tax account is used for tax-free turnover.
+func (e *Eur) isTaxFreeAccount(acc TaxAccount) bool {
+	return acc == taxFreeAccount || slices.ContainsFunc(e.taxFree, func(r TaxFreeRule) bool {
+		return r.Account == acc
+	})
+}
+
+// prepareTaxFree checks the rules for tax-free turnover.
+func (e *Eur) prepareTaxFree(rules []TaxFreeRule) {
+	for _, r := range rules {
+		if _, ok := taxFreeKz[r.Kz]; !ok {
+			log.Fatalf("Invalid Kennzahl %d for tax-free turnover of account %d.", r.Kz, r.Account)
+		}
+		if r.Account != taxFreeAccount && hasMapping(r.Account, Regular) {
+			log.Fatalf("Tax account %d is not a tax-free account.", r.Account)
+		}
+	}
+
+	e.taxFree = rules
+}
+
+// taxFreeCategory returns the category of the payment on the tax-free account.
+// Payments not matching any rule are `Regular` and not reported.
+func (e *Eur) taxFreeCategory(p *Payment, acc TaxAccount) Category {
+	for _, r := range e.taxFree {
+		if r.Account == acc && (len(r.BookingAccounts) == 0 || slices.Contains(r.BookingAccounts, p.Account)) {
+			return taxFreeKz[r.Kz]
+		}
+	}
+	return Regular
+}
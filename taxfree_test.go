@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTaxFreeCategory(t *testing.T) {
+	e := &Eur{}
+	e.prepareTaxFree([]TaxFreeRule{
+		{Account: 520, Kz: 41, BookingAccounts: []int{8125}},
+		{Account: 520, Kz: 21},
+		{Account: 530, Kz: 43},
+	})
+
+	tests := []struct {
+		acc     TaxAccount
+		account int
+		want    Category
+	}{
+		{520, 8125, IntraCommunityDelivery},
+		{520, 8338, EUService},
+		{530, 8120, Export},
+	}
+
+	for _, tt := range tests {
+		p := &Payment{Account: tt.account}
+		if got := e.taxFreeCategory(p, tt.acc); got != tt.want {
+			t.Errorf("taxFreeCategory(Kto %d/%d) = %s, want %s", tt.acc, tt.account, got, tt.want)
+		}
+	}
+
+	// further tax-free accounts are reported like the default one
+	key, _ := e.classify(&Payment{Account: 8120}, 530)
+	if want := (VatKey{taxFreeAccount, Export}); key != want {
+		t.Errorf("classify(Kto 530/8120) = %v, want %v", key, want)
+	}
+
+	// rules of one JES file do not leak into others
+	if (&Eur{}).isTaxFreeAccount(530) {
+		t.Errorf("Tax account 530 is tax-free without a rule")
+	}
+}
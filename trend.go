@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TrendEntry is the value of a Kennzahl in one UStVA period.
+type TrendEntry struct {
+	Period string
+	Amount Cents
+	Total  Cents  // running total since the start of the year
+	Prior  *Cents // value in the same period of the prior year, if known
+}
+
+// Change returns the difference to the same period of the prior year.
+func (e TrendEntry) Change() (Cents, bool) {
+	if e.Prior == nil {
+		return 0, false
+	}
+	return e.Amount - *e.Prior, true
+}
+
+// TrendRow holds the values of one Kennzahl, or of the Zahllast for `Kz` 0, over all periods of the year.
+type TrendRow struct {
+	Kz      int
+	Entries []TrendEntry
+}
+
+func (r TrendRow) Label() string {
+	if r.Kz == 0 {
+		return "Zahllast"
+	}
+	return strconv.Itoa(r.Kz)
+}
+
+// trendValues returns the amounts of all Kennzahlen of the periods. The Zahllast is stored as Kennzahl 0.
+func trendValues(conf *Config, jes []*Eur, periods []Period) []map[int]Cents {
+	values := make([]map[int]Cents, len(periods))
+	for i, period := range periods {
+		kennzahlen := periodKennzahlen(conf, jes, period)
+		values[i] = map[int]Cents{0: kennzahlen.TaxSum(conf.TaxComputation)}
+		for id, kz := range kennzahlen {
+			values[i][id] = kz.relevantAmount()
+		}
+	}
+	return values
+}
+
+// trendData computes the trend of all Kennzahlen over the UStVA periods of the year.
+// If `withPrior` is set, the values are compared to the same periods of the previous year.
+// The Sondervorauszahlung is not deducted from the Zahllast.
+func trendData(conf *Config, jes []*Eur, year Year, withPrior bool) []TrendRow {
+	periods := periodsOfYear(year, conf.Filing)
+	current := trendValues(conf, jes, periods)
+
+	var prior []map[int]Cents
+	if withPrior {
+		priorPeriods := make([]Period, len(periods))
+		for i, p := range periods {
+			priorPeriods[i] = p.inYear(year - 1)
+		}
+		prior = trendValues(conf, jes, priorPeriods)
+	}
+
+	ids := make(map[int]bool)
+	for _, values := range slices.Concat(current, prior) {
+		for id := range values {
+			ids[id] = true
+		}
+	}
+
+	var rows []TrendRow
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		row := TrendRow{Kz: id}
+		var total Cents
+		for i, p := range periods {
+			amount := current[i][id]
+			total += amount
+			entry := TrendEntry{Period: periodLabel(p), Amount: amount, Total: total}
+			if withPrior {
+				priorAmount := prior[i][id]
+				entry.Prior = &priorAmount
+			}
+			row.Entries = append(row.Entries, entry)
+		}
+		rows = append(rows, row)
+	}
+
+	// the Zahllast (id 0) is shown last
+	if len(rows) > 0 && rows[0].Kz == 0 {
+		rows = append(rows[1:], rows[0])
+	}
+	return rows
+}
+
+// WriteTrend prints one table per Kennzahl with the values of each period.
+func WriteTrend(w io.Writer, rows []TrendRow) {
+	format := "%8d,%02d EUR"
+
+	for i, r := range rows {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if r.Kz == 0 {
+			fmt.Fprintf(w, "%s\n", r.Label())
+		} else {
+			fmt.Fprintf(w, "Kz %s\n", r.Label())
+		}
+		fmt.Fprintf(w, "Zeitraum\tBetrag\t\t\tKumuliert\t\tVorjahr\t\t\tVeränderung\n")
+		for _, e := range r.Entries {
+			prior, change := "-\t\t", "-"
+			if delta, ok := e.Change(); ok {
+				prior = e.Prior.Format(format)
+				change = delta.Format(format)
+			}
+			fmt.Fprintf(w, "%s\t\t%s\t%s\t%s\t%s\n", e.Period, e.Amount.Format(format), e.Total.Format(format), prior, change)
+		}
+	}
+}
+
+// WriteTrendCSV writes the trend as CSV with one line per Kennzahl and period.
+func WriteTrendCSV(w io.Writer, rows []TrendRow) {
+	format := "%d.%02d"
+
+	csvWriter := csv.NewWriter(w)
+	records := [][]string{{"Kennzahl", "Zeitraum", "Betrag", "Kumuliert", "Vorjahr", "Veränderung"}}
+	for _, r := range rows {
+		for _, e := range r.Entries {
+			var prior, change string
+			if delta, ok := e.Change(); ok {
+				prior = e.Prior.Format(format)
+				change = delta.Format(format)
+			}
+			records = append(records, []string{r.Label(), e.Period, e.Amount.Format(format), e.Total.Format(format), prior, change})
+		}
+	}
+
+	if err := csvWriter.WriteAll(records); err != nil {
+		log.Fatalf("Writing trend: %v", err)
+	}
+}
+
+// cmdTrend handles
+//
+//	trend [-csv] <jes.file> [<jes.file>...] <year>
+//
+// If the JES files also cover the previous year, its periods are used for comparison.
+func cmdTrend(conf *Config, args []string) {
+	asCSV := false
+	if len(args) > 0 && args[0] == "-csv" {
+		asCSV = true
+		args = args[1:]
+	}
+
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jesFiles, args := splitJesArgs(args)
+	year := yearArg(args[0])
+	jes := loadJes(conf, jesFiles)
+
+	covered, withPrior := false, false
+	for _, e := range jes {
+		covered = covered || e.coversYear(int(year))
+		withPrior = withPrior || e.coversYear(int(year)-1)
+	}
+	if !covered {
+		log.Fatalf("Year %d is not covered by any of the JES files.", year)
+	}
+	if missing := missingMonths(jes, year); len(missing) > 0 {
+		log.Fatalf("Year %d is only partly covered by the JES files, missing: %s. "+
+			"Please also pass the JES files of the other business years.", year, strings.Join(missing, ", "))
+	}
+	if withPrior {
+		if missing := missingMonths(jes, year-1); len(missing) > 0 {
+			log.Printf("WARNING: Year %d is only partly covered by the JES files, missing: %s. "+
+				"The comparison to these periods is incomplete.", year-1, strings.Join(missing, ", "))
+		}
+	}
+
+	rows := trendData(conf, jes, year, withPrior)
+	if asCSV {
+		WriteTrendCSV(os.Stdout, rows)
+	} else {
+		WriteTrend(os.Stdout, rows)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrendData(t *testing.T) {
+	accounts := map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}}
+
+	prior := &Eur{Start: Date{2024, 1, 1}, End: Date{2024, 12, 31}, accountInfo: accounts}
+	prior.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 2, 1}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "200")}},
+	}
+	prior.Validate()
+
+	current := &Eur{Start: Date{2025, 1, 1}, End: Date{2025, 12, 31}, accountInfo: accounts}
+	current.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2025, 1, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100")}},
+		{Number: 2, Date: Date{2025, 7, 10}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "300")}},
+	}
+	current.Validate()
+
+	conf := &Config{Filing: filingQuarterly}
+	jes := []*Eur{prior, current}
+
+	rows := trendData(conf, jes, 2025, true)
+	if len(rows) != 2 || rows[0].Kz != 81 || rows[1].Kz != 0 {
+		t.Fatalf("rows = %+v, want Kz 81 and the Zahllast", rows)
+	}
+
+	tests := []struct {
+		period string
+		amount Cents
+		total  Cents
+		change Cents
+	}{
+		{"2025Q1", 100_00, 100_00, -100_00},
+		{"2025Q2", 0, 100_00, 0},
+		{"2025Q3", 300_00, 400_00, 300_00},
+		{"2025Q4", 0, 400_00, 0},
+	}
+	for i, tt := range tests {
+		e := rows[0].Entries[i]
+		change, ok := e.Change()
+		if e.Period != tt.period || e.Amount != tt.amount || e.Total != tt.total || !ok || change != tt.change {
+			t.Errorf("entry %d = %+v (change %s), want %+v", i, e, change, tt)
+		}
+	}
+
+	if e := rows[1].Entries[2]; e.Amount != 57_00 || e.Total != 76_00 {
+		t.Errorf("Zahllast Q3 = %+v, want 57.00 EUR (total 76.00 EUR)", e)
+	}
+
+	rows = trendData(conf, []*Eur{current}, 2025, false)
+	if _, ok := rows[0].Entries[0].Change(); ok {
+		t.Errorf("change known without prior year")
+	}
+
+	var buf bytes.Buffer
+	WriteTrendCSV(&buf, rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 9 || lines[1] != "81,2025Q1,100.00,100.00,," || lines[8] != "Zahllast,2025Q4,0.00,76.00,," {
+		t.Errorf("CSV = %q", lines)
+	}
+}
@@ -16,6 +16,11 @@ import (
 	"golang.org/x/text/encoding/charmap"
 )
 
+// legacyKz maps Kennzahlen written by earlier versions to the ones used now,
+// so that older UStVA XML files can still be read back.
+// The 7% turnover had been reported in Kz 83, the remaining Vorauszahlung, instead of Kz 86.
+var legacyKz = map[int]int{83: 86}
+
 type UStELine uint16
 
 func (l UStELine) String() string {
@@ -52,6 +57,9 @@ func (k *Kennzahlen) UnmarshalXML(d *xml.Decoder, elem xml.StartElement) error {
 	if err != nil {
 		return fmt.Errorf("invalid Kennzahl: %s", elem.Name.Local)
 	}
+	if current, ok := legacyKz[kz]; ok {
+		kz = current
+	}
 
 	data := struct {
 		Data string `xml:",chardata"`
@@ -69,27 +77,42 @@ func (k *Kennzahlen) UnmarshalXML(d *xml.Decoder, elem xml.StartElement) error {
 		*k = make(Kennzahlen)
 	}
 
-	mIdx := slices.IndexFunc(mappings, func(m Mapping) bool { return m.kz == kz })
-	if mIdx < 0 {
+	mapping, typ, found := mappingForKz(kz)
+	if !found {
 		k.Merge(kz, Kennzahl{typ: Ignore, amount: cents})
 	} else {
-		mapping := mappings[mIdx]
-
 		kennzahl := Kennzahl{
 			amount:       cents,
 			withFraction: strings.Contains(data.Data, "."),
-			typ:          mapping.typ,
+			typ:          typ,
 			account:      mapping.account,
 		}
+		if typ == Base {
+			kennzahl.taxKz = mapping.taxKz
+		}
 		k.Merge(kz, kennzahl)
 	}
 	return nil
 }
 
 func readUStVAXml(xmlFile string) Kennzahlen {
+	return readUStVA(xmlFile).Kennzahlen
+}
+
+// readUStVA reads the UStVA part of an XML file as written by WriteVatFile.
+func readUStVA(xmlFile string) *UStVA {
+	anmeldung := readAnmeldung(xmlFile)
+	if anmeldung.UStVA == nil {
+		log.Fatalf("XML file '%s' is not an UStVA.", xmlFile)
+	}
+	return anmeldung.UStVA
+}
+
+// readAnmeldung reads an XML file as written by WriteVatFile or the `dfv` command.
+func readAnmeldung(xmlFile string) *Anmeldung {
 	data, err := os.ReadFile(xmlFile)
 	if err != nil {
-		log.Fatalf("Could not read UStVA XML file '%s': %v", xmlFile, err)
+		log.Fatalf("Could not read XML file '%s': %v", xmlFile, err)
 	}
 
 	dec := xml.NewDecoder(bytes.NewReader(data))
@@ -104,12 +127,12 @@ func readUStVAXml(xmlFile string) Kennzahlen {
 
 	var anmeldung Anmeldung
 	if err = dec.Decode(&anmeldung); err != nil {
-		log.Fatalf("Error parsing UStVA XML file '%s': %v", xmlFile, err)
+		log.Fatalf("Error parsing XML file '%s': %v", xmlFile, err)
 	}
-	return anmeldung.UStVA.Kennzahlen
+	return &anmeldung
 }
 
-func OutputUStE(jes *Eur, period Period, xmls []string) {
+func OutputUStE(conf *Config, jes []*Eur, period Period, xmls []string) {
 	ustvas := make([]Kennzahlen, len(xmls))
 	for i, xmlFile := range xmls {
 		ustvas[i] = readUStVAXml(xmlFile)
@@ -124,20 +147,18 @@ func OutputUStE(jes *Eur, period Period, xmls []string) {
 
 	for id, kz := range combinedKz {
 		acc := combinedKz[id].account
-		kz.percent = jes.accountInfo[acc].Percent
+		kz.percent = accountPercent(jes, acc)
 	}
 
-	vatData := jes.VatData(period)
+	vatData, _ := mergedVatData(jes, period)
 	fullYearKz := kennzahlenFromVatData(vatData)
-
-	vzSum := combinedKz.TaxSum()
-	fySum := fullYearKz.TaxSum()
-
-	type lineKz struct {
-		fy *Kennzahl
-		vz *Kennzahl
+	if kz, ok := inputTaxCorrection(conf, jes, period); ok {
+		fullYearKz.Merge(KzVstBerichtigung, kz)
 	}
 
+	vzSum := combinedKz.TaxSum(conf.TaxComputation)
+	fySum := fullYearKz.TaxSum(conf.TaxComputation)
+
 	byLine := make(map[UStELine]lineKz)
 	for _, m := range mappings {
 		if m.typ == Ignore {
@@ -152,17 +173,29 @@ func OutputUStE(jes *Eur, period Period, xmls []string) {
 		vz := combinedKz[m.kz]
 		fy := fullYearKz[m.kz]
 
+		if vz == nil && fy != nil && m.kz == KzVstBerichtigung {
+			// smaller corrections are only part of the UStE
+			vz = copyKz(nil)
+		}
+
 		if vz == nil || fy == nil {
-			if (vz == nil) != (fy == nil) {
-				// should not happen
-				log.Panicf("Inconsistent data for Kennzahl %d", m.kz)
+			if vz == nil && fy != nil {
+				log.Fatalf("Kennzahl %d is part of the JES files, but missing in the UStVA XML files. "+
+					"Please check that all UStVAs of the year are given.", m.kz)
+			}
+			if vz != nil && fy == nil {
+				log.Fatalf("Kennzahl %d is part of the UStVA XML files, but missing in the JES files. "+
+					"Please check that the XML files belong to the JES files.", m.kz)
 			}
 			continue
 		}
 
-		fyCopy := *fy
-		vzCopy := *vz
-		byLine[m.zeile] = lineKz{fy: &fyCopy, vz: &vzCopy}
+		line := lineKz{fy: copyKz(fy), vz: copyKz(vz)}
+		if m.typ == Base {
+			line.fyTax = copyKz(fullYearKz[m.taxKz])
+			line.vzTax = copyKz(combinedKz[m.taxKz])
+		}
+		byLine[m.zeile] = line
 	}
 
 	lines := slices.SortedFunc(maps.Keys(byLine), func(line, line2 UStELine) int {
@@ -173,35 +206,130 @@ func OutputUStE(jes *Eur, period Period, xmls []string) {
 	})
 
 	for _, zeile := range lines {
-		line := byLine[zeile]
-		printLine(line.fy, line.vz, zeile)
+		printLine(byLine[zeile], zeile, conf.TaxComputation)
 	}
 
 	sumKz := func(amt Cents) *Kennzahl {
 		return &Kennzahl{typ: Tax, amount: amt, withFraction: true}
 	}
 
-	printLine(sumKz(vzSum), sumKz(fySum), 119)
+	printLine(lineKz{fy: sumKz(fySum), vz: sumKz(vzSum)}, 119, conf.TaxComputation)
+
+	WriteBalance(os.Stdout, balanceData(conf, jes, period.Year(), combinedKz, fySum, vzSum))
+}
+
+// Balance is the remaining Abschlusszahlung or Erstattung of a year: the annual tax minus the filed prepayments
+// and the Sondervorauszahlung. It is also compared to the tax payments booked in JES.
+type Balance struct {
+	Tax         Cents
+	Prepayments Cents // sum of the Zahllast of each UStVA
+	Svz         Cents
+	Booked      Cents // bookings matched to the UStVA periods of the year and the Sondervorauszahlung
+	Unallocated Cents // bookings of the year not matched, e.g. the payment for December of the previous year
+}
+
+func (b Balance) Amount() Cents {
+	return b.Tax - b.Prepayments - b.Svz
+}
+
+// Open returns the tax of the year not yet paid according to the bookings.
+func (b Balance) Open() Cents {
+	return b.Tax - b.Booked
+}
+
+// balanceData computes the balance of the year. The bookings are matched to the UStVA periods like in `reconcile`,
+// so that payments made in the following year are attributed to the period they are for.
+func balanceData(conf *Config, jes []*Eur, year Year, combinedKz Kennzahlen, fySum, vzSum Cents) Balance {
+	// The Sondervorauszahlung is usually deducted in the final UStVA (Kz 39).
+	// If it is not, it has still been paid and is taken from the config/store.
+	var svzFiled Cents
+	if kz, ok := combinedKz[KzSvz]; ok {
+		svzFiled = kz.amount
+	}
+	svz := svzFiled
+	if svz == 0 {
+		svz, _ = knownSvz(conf, int(year))
+	}
+
+	b := Balance{Tax: fySum, Prepayments: vzSum - svzFiled, Svz: svz}
+
+	entries := reconcileEntries(conf, jes, year)
+	for _, booking := range reconcile(entries, allTaxBookings(jes)) {
+		if year.includes(booking.Date) {
+			b.Unallocated += booking.Amount
+		}
+	}
+	for _, e := range entries {
+		if e.Booking != nil {
+			b.Booked += e.Booking.Amount
+		}
+	}
+
+	return b
+}
+
+// WriteBalance prints the balance of the year.
+func WriteBalance(w io.Writer, b Balance) {
+	balanceStr := "Abschlusszahlung"
+	if b.Amount() < 0 {
+		balanceStr = "Erstattung"
+	}
+
+	format := "%8d,%02d EUR"
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, " Steuer (Jahr)\t\t\t%s\n", b.Tax.Format(format))
+	fmt.Fprintf(w, " - Vorauszahlungen (UStVA)\t%s\n", b.Prepayments.Format(format))
+	fmt.Fprintf(w, " - Sondervorauszahlung\t\t%s\n", b.Svz.Format(format))
+	fmt.Fprintf(w, " = %s\t\t%s\n", balanceStr, b.Amount().Format(format))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, " Gebuchte Zahlungen (JES)\t%s\n", b.Booked.Format(format))
+	fmt.Fprintf(w, " = Offen lt. Buchhaltung\t%s\n", b.Open().Format(format))
+	if b.Unallocated != 0 {
+		fmt.Fprintf(w, " Nicht zugeordnet (JES)\t\t%s\n", b.Unallocated.Format(format))
+	}
+}
+
+// lineKz holds the full year (`fy`) and the prepayment (`vz`) values of one UStE line.
+// For `Base` Kennzahlen, the explicit taxes are held in `fyTax` and `vzTax`.
+type lineKz struct {
+	fy, vz       *Kennzahl
+	fyTax, vzTax *Kennzahl
+}
+
+func copyKz(kz *Kennzahl) *Kennzahl {
+	if kz == nil {
+		return &Kennzahl{typ: Tax, withFraction: true}
+	}
+	kzCopy := *kz
+	return &kzCopy
 }
 
-func printLine(fullYear *Kennzahl, vz *Kennzahl, zeile UStELine) {
-	delta := fullYear.taxAmount() - vz.taxAmount()
+func printLine(line lineKz, zeile UStELine, computation string) {
+	fullYear, vz := line.fy, line.vz
+	delta := fullYear.taxAmount(computation) - vz.taxAmount(computation)
 
-	if fullYear.typ == AmountOnly {
+	switch fullYear.typ {
+	case AmountOnly:
 		delta = fullYear.relevantAmount() - vz.relevantAmount()
+	case Base:
+		delta = line.fyTax.relevantAmount() - line.vzTax.relevantAmount()
 	}
 
 	fmt.Printf(" %s\t=>\t%s", zeile, fullYear.relevantAmount().Format("%5d,%02d EUR"))
 
-	if fullYear.typ == Amount {
-		fmt.Printf("\t(%s", fullYear.taxAmount().Format("%5d,%02d EUR"))
+	switch fullYear.typ {
+	case Amount:
+		fmt.Printf("\t(%s", fullYear.taxAmount(computation).Format("%5d,%02d EUR"))
+	case Base:
+		fmt.Printf("\t(%s", line.fyTax.relevantAmount().Format("%5d,%02d EUR"))
 	}
 
 	if delta != 0 {
 		fmt.Printf("\tΔ %s", delta.Format("%d,%02d EUR"))
 	}
 
-	if fullYear.typ == Amount {
+	if fullYear.typ == Amount || fullYear.typ == Base {
 		fmt.Print(")")
 	}
 
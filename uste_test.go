@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBalanceYearBoundary(t *testing.T) {
+	conf := &Config{Filing: filingMonthly, Svz: map[int]Cents{2024: 0}}
+
+	newEur := func(year int, receipts ...*Receipt) *Eur {
+		e := &Eur{Start: Date{year, 1, 1}, End: Date{year, 12, 31},
+			accountInfo:        map[TaxAccount]Account{500: {Number: 500, Percent: 19 * pct}},
+			taxBookingAccounts: map[int]struct{}{1780: {}},
+			Receipts:           receipts,
+		}
+		e.Validate()
+		return e
+	}
+
+	current := newEur(2024,
+		// payment for December of the previous year
+		&Receipt{Number: 1, Date: Date{2024, 1, 10}, Paid: true, Payments: []*Payment{newPayment(0, 1780, "50")}},
+		&Receipt{Number: 2, Date: Date{2024, 11, 5}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "100")}},
+		&Receipt{Number: 3, Date: Date{2024, 12, 10}, Paid: true, Payments: []*Payment{newPayment(0, 1780, "19")}},
+		&Receipt{Number: 4, Date: Date{2024, 12, 15}, Paid: true, Payments: []*Payment{newPayment(500, 8400, "200")}},
+	)
+	// payment for December, booked in the following year
+	following := newEur(2025,
+		&Receipt{Number: 1, Date: Date{2025, 1, 10}, Paid: true, Payments: []*Payment{newPayment(0, 1780, "38")}},
+	)
+
+	tests := []struct {
+		name string
+		jes  []*Eur
+		want Balance
+	}{
+		{"current year", []*Eur{current}, Balance{Tax: 57_00, Prepayments: 57_00, Booked: 19_00, Unallocated: 50_00}},
+		{"with following year", []*Eur{current, following}, Balance{Tax: 57_00, Prepayments: 57_00, Booked: 57_00, Unallocated: 50_00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := balanceData(conf, tt.jes, 2024, Kennzahlen{}, 57_00, 57_00); got != tt.want {
+				t.Errorf("balanceData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	WriteBalance(&buf, Balance{Tax: 57_00, Prepayments: 57_00, Booked: 19_00, Unallocated: 50_00})
+	for _, want := range []string{"Offen lt. Buchhaltung\t      38,00 EUR", "Nicht zugeordnet (JES)\t\t      50,00 EUR"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteBalance output = %q, want %q", buf.String(), want)
+		}
+	}
+}
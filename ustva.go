@@ -23,6 +23,7 @@ const (
 	Amount             // tax is calculated based on the net amount
 	AmountOnly         // no tax calulation, explicitly use the net amount
 	Tax                // tax is exactly the paid taxes
+	Base               // net amount, whose tax is given explicitly in a paired Kennzahl (`Mapping.taxKz`)
 )
 
 func (s SumType) String() string {
@@ -33,18 +34,24 @@ func (s SumType) String() string {
 		return "Amt"
 	case Tax:
 		return "Tax"
+	case Base:
+		return "Bas"
 	default:
 		return "Unknown"
 	}
 }
 
 // Mapping of JES account types to Elster-Kennzahlen.
+// `category` allows to map payments of one account to different Kennzahlen.
 // `typ` specifies whether we sum gross amounts or taxes.
+// For `Base`, `taxKz` is the Kennzahl holding the explicit tax of the net amount in `kz`.
 type Mapping struct {
-	kz      int
-	zeile   UStELine // UStE Zeile
-	account TaxAccount
-	typ     SumType
+	kz       int
+	taxKz    int
+	zeile    UStELine // UStE Zeile
+	account  TaxAccount
+	category Category
+	typ      SumType
 }
 
 const (
@@ -54,39 +61,91 @@ const (
 
 var mappings = []Mapping{
 	// Steuerpflichtige Umsätze 19%
-	{81, 22, 500, Amount},
+	{81, NA, 22, 500, Regular, Amount},
+	{81, NA, 22, 500, PrivateUse, Amount},
 	// Steuerpflichtige Umsätze 7%
-	{83, 25, 510, Amount},
+	{86, NA, 25, 510, Regular, Amount},
+	{86, NA, 25, 510, PrivateUse, Amount},
+	// Umsätze zu anderen Steuersätzen
+	{35, 36, 28, 500, OtherRate, Base},
+	{35, 36, 28, 510, OtherRate, Base},
 	// Steuerpflichtige Umsätze 0%
 	// This is not reproduced in JES, as there is a difference between taxed with 0% and taxfree.
 	// Account 520 is used for taxfree, and is therefore not applicable here.
-	// USt 0% (Steuerfrei) --> Ignore
-	{NA, NA, 520, Ignore},
+	// USt 0% (Steuerfrei) --> Ignore, unless classified via `TaxFreeRule`
+	{NA, NA, NA, 520, Regular, Ignore},
+	// Steuerfreie Umsätze mit Vorsteuerabzug
+	{41, NA, 38, 520, IntraCommunityDelivery, AmountOnly},
+	{42, NA, 40, 520, Triangular, AmountOnly},
+	{43, NA, 41, 520, Export, AmountOnly},
+	// Steuerfreie Umsätze ohne Vorsteuerabzug
+	{48, NA, 45, 520, TaxFreeNoDeduction, AmountOnly},
+	// Nicht steuerbare Umsätze
+	{21, NA, 103, 520, EUService, AmountOnly},
+	{45, NA, 104, 520, NotTaxable, AmountOnly},
 	// VSt 19%
-	{66, 79, 100, Tax},
+	{66, NA, 79, 100, Regular, Tax},
 	// VSt 7%
-	{66, 79, 110, Tax},
+	{66, NA, 79, 110, Regular, Tax},
+	// non-deductible part of partially deductible input tax --> Ignore
+	{NA, NA, NA, 100, NonDeductible, Ignore},
+	{NA, NA, NA, 110, NonDeductible, Ignore},
+	{NA, NA, NA, 200, NonDeductible, Ignore},
+	{NA, NA, NA, 250, NonDeductible, Ignore},
+	{NA, NA, NA, 255, NonDeductible, Ignore},
 	// Vst 0% --> Ignore
-	{NA, NA, 120, Ignore},
+	{NA, NA, NA, 120, Regular, Ignore},
 	// §13b UStG USt
-	{46, 6501, 600, AmountOnly},
-	{47, 6502, 600, Tax},
+	{46, 47, 65, 600, Regular, Base},
+	{52, 53, 66, 600, ReverseCharge52, Base},
+	{73, 74, 67, 600, ReverseCharge73, Base},
+	{78, 79, 68, 600, ReverseCharge78, Base},
+	{84, 85, 69, 600, ReverseCharge84, Base},
 	// §13b UStG VSt
-	{67, 83, 200, Tax},
+	{67, NA, 83, 200, Regular, Tax},
 	// Innergemeinschaftlicher Erwerb
-	{89, 51, 650, Amount},
-	{93, 52, 655, Amount},
-	{61, 80, 250, Tax},
-	{61, 80, 255, Tax},
+	{89, NA, 51, 650, Regular, Amount},
+	{93, NA, 52, 655, Regular, Amount},
+	{95, 98, 53, 650, OtherRate, Base},
+	{95, 98, 53, 655, OtherRate, Base},
+	{61, NA, 80, 250, Regular, Tax},
+	{61, NA, 80, 255, Regular, Tax},
+	// Berichtigung des Vorsteuerabzugs (§15a UStG), not booked in JES
+	{KzVstBerichtigung, NA, 85, NA, Regular, Tax},
 	// TODO: Einfuhrumsatzsteuer
 }
 
-func init() {
+// hasMapping returns whether payments of the account in the given category are mapped to a Kennzahl.
+func hasMapping(account TaxAccount, category Category) bool {
+	return slices.ContainsFunc(mappings, func(m Mapping) bool {
+		return m.account == account && m.category == category
+	})
+}
+
+// mappingForKz returns the first mapping filling the given Kennzahl, together with the SumType of the Kennzahl.
+// For the tax Kennzahl of a `Base` mapping, this is `Tax`.
+func mappingForKz(kz int) (Mapping, SumType, bool) {
+	for _, m := range mappings {
+		switch kz {
+		case m.kz:
+			return m, m.typ, true
+		case m.taxKz:
+			return m, Tax, true
+		}
+	}
+	return Mapping{}, Ignore, false
+}
+
+func sortMappings() {
 	slices.SortFunc(mappings, func(a, b Mapping) int {
-		return cmp.Or(cmp.Compare(a.kz, b.kz), cmp.Compare(a.account, b.account))
+		return cmp.Or(cmp.Compare(a.kz, b.kz), cmp.Compare(a.account, b.account), cmp.Compare(a.category, b.category))
 	})
 }
 
+func init() {
+	sortMappings()
+}
+
 const (
 	// Sondervorauszahlung
 	KzSvz = 39
@@ -102,7 +161,8 @@ type Anmeldung struct {
 	Date           string         `xml:"Erstellungsdatum"`
 	Datenlieferant Datenlieferant `xml:"DatenLieferant"`
 	Unternehmer    Unternehmer    `xml:"Steuerfall>Unternehmer"`
-	UStVA          UStVA          `xml:"Steuerfall>Umsatzsteuervoranmeldung"`
+	UStVA          *UStVA         `xml:"Steuerfall>Umsatzsteuervoranmeldung,omitempty"`
+	DFV            *DFV           `xml:"Steuerfall>Dauerfristverlaengerung,omitempty"`
 }
 
 // Datenlieferant holds data about who processed the data.
@@ -146,10 +206,20 @@ type Kennzahl struct {
 	withFraction bool
 	amount       Cents
 	account      TaxAccount
-	percent      int
+	percent      Rate
 	typ          SumType
+	taxKz        int // Kennzahl of the explicit tax, only for `Base`
+	// sum of the taxes of the single payments, only for `Amount`
+	receiptTax   Cents
+	fromReceipts bool // whether `receiptTax` is known, i.e. not read back from a filed UStVA
 }
 
+// Ways to derive the tax of `Amount` Kennzahlen.
+const (
+	taxPerTotal   = "total"   // from the base in full euros, as ELSTER does
+	taxPerReceipt = "receipt" // sum of the taxes of the single payments, as booked in JES
+)
+
 // Kennzahlen represents all filled fields on the UStVA form.
 // It maps the field number to its content.
 type Kennzahlen map[int]*Kennzahl
@@ -169,18 +239,29 @@ func (k *Kennzahl) relevantAmount() Cents {
 	}
 }
 
-func (k *Kennzahl) taxAmount() Cents {
+// taxAmount returns the tax of the Kennzahl. The tax of `Amount` Kennzahlen is derived according to `computation`,
+// i.e. the configured `taxComputation`.
+func (k *Kennzahl) taxAmount(computation string) Cents {
 	switch k.typ {
-	case AmountOnly, Ignore:
+	case AmountOnly, Base, Ignore:
+		// for Base, the tax is part of the paired Kennzahl
 		return 0
 	case Tax:
 		return k.relevantAmount()
 	case Amount:
-		return k.relevantAmount().Percentage(k.percent)
+		if computation == taxPerReceipt && k.fromReceipts {
+			return k.receiptTax
+		}
+		return k.totalTax()
 	}
 	return 0
 }
 
+// totalTax returns the tax of an `Amount` Kennzahl as computed by ELSTER from the base in full euros.
+func (k *Kennzahl) totalTax() Cents {
+	return k.relevantAmount().Percentage(k.percent)
+}
+
 func (k Kennzahlen) Merge(id int, kz Kennzahl) {
 	other, ok := k[id]
 	if !ok {
@@ -189,8 +270,8 @@ func (k Kennzahlen) Merge(id int, kz Kennzahl) {
 	}
 
 	// Assertions of consistency
-	if kz.typ != Tax && kz.percent != other.percent {
-		log.Fatalf("Inconsistent tax rate for Kz %d: %d vs %d", id, kz.percent, other.percent)
+	if kz.typ == Amount && kz.percent != other.percent {
+		log.Fatalf("Inconsistent tax rate for Kz %d: %s%% vs %s%%", id, kz.percent, other.percent)
 	}
 	if kz.typ != other.typ {
 		log.Fatalf("Inconsistent mapping for Kz %d: %d vs %d", id, kz.typ, other.typ)
@@ -200,16 +281,30 @@ func (k Kennzahlen) Merge(id int, kz Kennzahl) {
 	}
 
 	k[id].amount += kz.amount
+	k[id].receiptTax += kz.receiptTax
+	k[id].fromReceipts = k[id].fromReceipts && kz.fromReceipts
+}
+
+// periodKennzahlen computes the Kennzahlen of the UStVA of the period, including the Vorsteuerberichtigung.
+func periodKennzahlen(conf *Config, jes []*Eur, period Period) Kennzahlen {
+	debug("=== %s ===", periodLabel(period))
+	vatData, _ := mergedVatData(jes, period)
+	kennzahlen := kennzahlenFromVatData(vatData)
+	if kz, ok := inputTaxCorrection(conf, jes, period); ok {
+		kennzahlen.Merge(KzVstBerichtigung, kz)
+	}
+	return kennzahlen
 }
 
-func (k Kennzahlen) TaxSum() Cents {
+// TaxSum returns the Zahllast of the Kennzahlen, see `taxAmount` for `computation`.
+func (k Kennzahlen) TaxSum(computation string) Cents {
 	var sum Cents
 
 	sortedKeys := slices.Sorted(maps.Keys(k))
 
 	for _, id := range sortedKeys {
 		kz := k[id]
-		amt := kz.taxAmount()
+		amt := kz.taxAmount(computation)
 		debug("* %d => %s", id, amt)
 
 		if kz.account.IsExpense() {
@@ -230,7 +325,7 @@ func kennzahlenFromVatData(vatData VatData) Kennzahlen {
 			continue
 		}
 
-		vat, ok := vatData[m.account]
+		vat, ok := vatData[VatKey{m.account, m.category}]
 		if !ok {
 			continue
 		}
@@ -238,7 +333,7 @@ func kennzahlenFromVatData(vatData VatData) Kennzahlen {
 		if !vat.Empty() {
 			var val Cents
 			switch m.typ {
-			case Amount, AmountOnly:
+			case Amount, AmountOnly, Base:
 				val = vat.NetAmount
 			case Tax:
 				val = vat.Tax
@@ -252,10 +347,28 @@ func kennzahlenFromVatData(vatData VatData) Kennzahlen {
 				typ:          m.typ,
 				account:      m.account,
 				percent:      vat.Percent,
+				taxKz:        m.taxKz,
+			}
+			if m.typ == Amount {
+				kz.receiptTax = vat.Tax
+				kz.fromReceipts = true
 			}
 			kennzahlen.Merge(m.kz, kz)
 
-			debug("\t=> Kz %02d (Kto %d, %s):\t%s\t(= %s)", m.kz, m.account, m.typ, val, kz.amountString())
+			debug("\t=> Kz %02d (Kto %d/%s, %s):\t%s\t(= %s)", m.kz, m.account, m.category, m.typ, val, kz.amountString())
+
+			if m.typ == Base {
+				taxKz := Kennzahl{
+					withFraction: true,
+					amount:       vat.Tax,
+					typ:          Tax,
+					account:      m.account,
+					percent:      vat.Percent,
+				}
+				kennzahlen.Merge(m.taxKz, taxKz)
+
+				debug("\t=> Kz %02d (Kto %d/%s, %s):\t%s\t(= %s)", m.taxKz, m.account, m.category, Tax, vat.Tax, taxKz.amountString())
+			}
 		}
 	}
 
@@ -263,10 +376,9 @@ func kennzahlenFromVatData(vatData VatData) Kennzahlen {
 }
 
 // fillUStVA generates the content for the UStVA fields.
-func fillUStVA(conf *Config, jesData *Eur, period Period, svz Cents) UStVA {
-	vatData := jesData.VatData(period)
-	ustva := UStVA{
-		Jahr:         jesData.Year(),
+func fillUStVA(conf *Config, vatData VatData, period Period, svz Cents) *UStVA {
+	ustva := &UStVA{
+		Jahr:         int(period.Year()),
 		Zeitraum:     period.String(),
 		Steuernummer: conf.UStNr,
 		WIdNr:        conf.WIdNr,
@@ -300,20 +412,51 @@ func (k Kennzahlen) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
 	return nil
 }
 
-func anmeldungForYear(year int) *Anmeldung {
+// Kinds of Anmeldungen, as used in the XML namespace.
+const (
+	kindUStVA = "ustva"
+	kindDFV   = "dauerfristverlaengerung"
+)
+
+// creationDate fixes the Erstellungsdatum of all Anmeldungen, if set.
+// It is given by the -date option or the SOURCE_DATE_EPOCH environment variable.
+var creationDate time.Time
+
+// sourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH environment variable, if set.
+// See https://reproducible-builds.org/specs/source-date-epoch/
+func sourceDateEpoch() (time.Time, bool, error) {
+	epoch, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || epoch == "" {
+		return time.Time{}, false, nil
+	}
+
+	secs, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid SOURCE_DATE_EPOCH '%s'", epoch)
+	}
+	return time.Unix(secs, 0).UTC(), true, nil
+}
+
+// creationTime returns the time to use as creation date of generated files.
+func creationTime() time.Time {
+	if !creationDate.IsZero() {
+		return creationDate
+	}
+	return now()
+}
+
+func anmeldungForYear(kind string, year int) *Anmeldung {
 	yearStr := strconv.Itoa(year)
 
 	name := xml.Name{
 		Local: "Anmeldungssteuern",
-		Space: "http://finkonsens.de/elster/elsteranmeldung/ustva/v" + yearStr,
+		Space: "http://finkonsens.de/elster/elsteranmeldung/" + kind + "/v" + yearStr,
 	}
 
-	now := time.Now()
-
 	anmeldung := Anmeldung{
 		XMLName: name,
 		Version: yearStr,
-		Date:    now.Format("20060102"),
+		Date:    creationTime().Format("20060102"),
 	}
 
 	return &anmeldung
@@ -360,18 +503,12 @@ func fillUnternehmer(conf *Config, jesData *Eur) Unternehmer {
 	}
 }
 
-// WriteVatFile writes the UStVA XML to the given Writer.
-func WriteVatFile(w io.Writer, conf *Config, jesData *Eur, period Period, svz Cents) {
+// writeAnmeldung encodes the Anmeldung as XML in the format requested by Elster.
+func writeAnmeldung(w io.Writer, a *Anmeldung) {
 	// ISO-8859-15 is requested
 	isoEncoder := charmap.ISO8859_15.NewEncoder()
 	w = isoEncoder.Writer(w)
 
-	// fill data
-	a := anmeldungForYear(jesData.Year())
-	a.Datenlieferant = fillDatenlieferant(conf, jesData)
-	a.Unternehmer = fillUnternehmer(conf, jesData)
-	a.UStVA = fillUStVA(conf, jesData, period, svz)
-
 	// write the header
 	if _, err := io.WriteString(w, header); err != nil {
 		log.Fatalf("Writing XML: %v", err)
@@ -385,12 +522,60 @@ func WriteVatFile(w io.Writer, conf *Config, jesData *Eur, period Period, svz Ce
 	if err := xmlEncoder.Encode(a); err != nil {
 		log.Fatalf("Error encoding XML: %v", err)
 	}
+}
+
+// WriteVatFile writes the UStVA XML to the given Writer.
+// If more than one JES file is given, their data is merged. General data is taken from the first one.
+func WriteVatFile(w io.Writer, conf *Config, jes []*Eur, period Period, svz Cents) {
+	vatData, perFile := mergedVatData(jes, period)
+
+	// fill data
+	a := anmeldungForYear(kindUStVA, int(period.Year()))
+	a.Datenlieferant = fillDatenlieferant(conf, jes[0])
+	a.Unternehmer = fillUnternehmer(conf, jes[0])
+	a.UStVA = fillUStVA(conf, vatData, period, svz)
+	if kz, ok := inputTaxCorrection(conf, jes, period); ok {
+		a.UStVA.Kennzahlen.Merge(KzVstBerichtigung, kz)
+	}
+
+	writeAnmeldung(w, a)
 
-	taxSum := a.UStVA.Kennzahlen.TaxSum()
+	taxSum := a.UStVA.Kennzahlen.TaxSum(conf.TaxComputation)
 	fmt.Fprintf(os.Stderr, "*** Expected Tax Sum: %s ***\n", taxSum)
+	WriteTaxDifferences(os.Stderr, a.UStVA.Kennzahlen)
+	WritePrivateUse(os.Stderr, vatData)
+	warnNegativeBases(a.UStVA.Kennzahlen)
+	for _, e := range jes {
+		WriteCorrections(os.Stderr, e.corrections(period))
+		WriteBadDebts(os.Stderr, e.badDebts(period))
+		WriteNonDeductible(os.Stderr, e.nonDeductible(period))
+	}
+
+	if len(jes) > 1 {
+		for i, e := range jes {
+			debug("=== %s ===", e.file)
+			fileSum := kennzahlenFromVatData(perFile[i]).TaxSum(conf.TaxComputation)
+			fmt.Fprintf(os.Stderr, "    - %s: %s\n", e.file, fileSum)
+		}
+	}
+}
+
+// WriteTaxDifferences reports the tax of each `Amount` Kennzahl computed from the total base and
+// as sum of the single payments, together with the rounding difference.
+func WriteTaxDifferences(w io.Writer, k Kennzahlen) {
+	for _, id := range slices.Sorted(maps.Keys(k)) {
+		kz := k[id]
+		if kz.typ != Amount || !kz.fromReceipts {
+			continue
+		}
+
+		total := kz.totalTax()
+		fmt.Fprintf(w, "    Kz %02d: per total %s, per receipt %s, difference %s\n",
+			id, total, kz.receiptTax, kz.receiptTax-total)
+	}
 }
 
 // BuildVatFile prints the UStVA XML to Stdout.
-func BuildVatFile(conf *Config, jesData *Eur, period Period, svz Cents) {
-	WriteVatFile(os.Stdout, conf, jesData, period, svz)
+func BuildVatFile(conf *Config, jes []*Eur, period Period, svz Cents) {
+	WriteVatFile(os.Stdout, conf, jes, period, svz)
 }
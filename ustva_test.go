@@ -3,7 +3,11 @@ package main
 import (
 	"bytes"
 	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAmountString(t *testing.T) {
@@ -49,3 +53,172 @@ func TestMarshalXML(t *testing.T) {
 		t.Errorf("MarshalXML output = %q, want %q", got, want)
 	}
 }
+
+func TestReadUStVALegacy(t *testing.T) {
+	// written by earlier versions, with the 7% turnover in Kz 83
+	old := `<?xml version="1.0" encoding="ISO-8859-15"?>
+<Anmeldungssteuern version="2024"><Steuerfall><Umsatzsteuervoranmeldung>
+<Jahr>2024</Jahr><Zeitraum>01</Zeitraum><Steuernummer>1234567890123</Steuernummer>
+<Kz81>100</Kz81><Kz83>200</Kz83>
+</Umsatzsteuervoranmeldung></Steuerfall></Anmeldungssteuern>`
+
+	xmlFile := filepath.Join(t.TempDir(), "ustva.xml")
+	if err := os.WriteFile(xmlFile, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	k := readUStVAXml(xmlFile)
+	if _, ok := k[83]; ok {
+		t.Errorf("Kz 83 still present: %v", k)
+	}
+	if kz := k[86]; kz == nil || kz.amount != 200_00 || kz.typ != Amount || kz.account != 510 {
+		t.Errorf("Kz 86 = %v, want 200 EUR on account 510", kz)
+	}
+	if kz := k[81]; kz == nil || kz.amount != 100_00 {
+		t.Errorf("Kz 81 = %v, want 100 EUR", kz)
+	}
+}
+
+func TestKennzahlenFromVatDataBase(t *testing.T) {
+	vatData := VatData{
+		{500, OtherRate}: {Tax: 1600, NetAmount: 10050, Percent: 16 * pct},
+		{510, OtherRate}: {Tax: 500, NetAmount: 10000, Percent: 5 * pct},
+		{600, Regular}:   {Tax: 1900, NetAmount: 10000, Percent: 19 * pct},
+		{500, Regular}:   {Tax: 1900, NetAmount: 10000, Percent: 19 * pct},
+	}
+
+	k := kennzahlenFromVatData(vatData)
+
+	tests := []struct {
+		kz     int
+		typ    SumType
+		amount string
+	}{
+		{35, Base, "200"},
+		{36, Tax, "21.00"},
+		{46, Base, "100"},
+		{47, Tax, "19.00"},
+		{81, Amount, "100"},
+	}
+
+	for _, tt := range tests {
+		kz, ok := k[tt.kz]
+		if !ok {
+			t.Errorf("Kz %d missing", tt.kz)
+			continue
+		}
+		if kz.typ != tt.typ || kz.amountString() != tt.amount {
+			t.Errorf("Kz %d = %s (%s), want %s (%s)", tt.kz, kz.amountString(), kz.typ, tt.amount, tt.typ)
+		}
+	}
+
+	// 21.00 (Kz 36) + 19.00 (Kz 47) + 19.00 (Kz 81)
+	if got, want := k.TaxSum(taxPerTotal), Cents(5900); got != want {
+		t.Errorf("TaxSum() = %s, want %s", got, want)
+	}
+}
+
+func TestMappingForKz(t *testing.T) {
+	tests := []struct {
+		kz      int
+		account TaxAccount
+		typ     SumType
+		found   bool
+	}{
+		{81, 500, Amount, true},
+		{46, 600, Base, true},
+		{47, 600, Tax, true},
+		{36, 500, Tax, true},
+		{39, 0, Ignore, false},
+	}
+
+	for _, tt := range tests {
+		m, typ, found := mappingForKz(tt.kz)
+		if found != tt.found || typ != tt.typ || m.account != tt.account {
+			t.Errorf("mappingForKz(%d) = Kto %d, %s, %v; want Kto %d, %s, %v",
+				tt.kz, m.account, typ, found, tt.account, tt.typ, tt.found)
+		}
+	}
+}
+
+func TestCreationDate(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	defer func(orig time.Time) { creationDate = orig }(creationDate)
+
+	now = func() time.Time { return time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC) }
+	creationDate = time.Time{}
+	if got := anmeldungForYear(kindUStVA, 2025).Date; got != "20250307" {
+		t.Errorf("Erstellungsdatum without fixed date = %s, want 20250307", got)
+	}
+
+	creationDate = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	write := func() string {
+		a := anmeldungForYear(kindUStVA, 2024)
+		a.UStVA = &UStVA{Jahr: 2024, Zeitraum: "01", Kennzahlen: Kennzahlen{81: {amount: 10000}}}
+		var sb strings.Builder
+		writeAnmeldung(&sb, a)
+		return sb.String()
+	}
+
+	first := write()
+	now = func() time.Time { return time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC) }
+	if second := write(); first != second {
+		t.Errorf("output differs between runs:\n%s\n%s", first, second)
+	}
+	if !strings.Contains(first, "<Erstellungsdatum>20240102</Erstellungsdatum>") {
+		t.Errorf("output misses the fixed Erstellungsdatum:\n%s", first)
+	}
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1704153600")
+	got, ok, err := sourceDateEpoch()
+	if err != nil || !ok || !got.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("sourceDateEpoch() = %v, %v, %v; want 2024-01-02", got, ok, err)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if _, ok, err := sourceDateEpoch(); ok || err != nil {
+		t.Errorf("sourceDateEpoch() with empty variable = %v, %v; want unset", ok, err)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, _, err := sourceDateEpoch(); err == nil {
+		t.Errorf("sourceDateEpoch() with invalid value succeeded")
+	}
+}
+
+func TestTaxComputation(t *testing.T) {
+	vatData := VatData{
+		// 100.99 EUR base, taxes of the single receipts sum up to 19.20 EUR
+		{500, Regular}: {Tax: 1920, NetAmount: 10099, Percent: 19 * pct},
+		{100, Regular}: {Tax: 500, NetAmount: 2630, Percent: 19 * pct},
+	}
+	k := kennzahlenFromVatData(vatData)
+
+	if got, want := k.TaxSum(taxPerTotal), Cents(1900-500); got != want {
+		t.Errorf("TaxSum() per total = %s, want %s", got, want)
+	}
+
+	if got, want := k.TaxSum(""), Cents(1900-500); got != want {
+		t.Errorf("TaxSum() by default = %s, want %s", got, want)
+	}
+
+	if got, want := k.TaxSum(taxPerReceipt), Cents(1920-500); got != want {
+		t.Errorf("TaxSum() per receipt = %s, want %s", got, want)
+	}
+
+	// Kennzahlen read back from a filed UStVA lack the receipt taxes
+	filed := Kennzahlen{81: {amount: 10000, typ: Amount, account: 500, percent: 19 * pct}}
+	if got, want := filed.TaxSum(taxPerReceipt), Cents(1900); got != want {
+		t.Errorf("TaxSum() per receipt of filed UStVA = %s, want %s", got, want)
+	}
+
+	var sb strings.Builder
+	WriteTaxDifferences(&sb, k)
+	want := "    Kz 81: per total 19.00 EUR, per receipt 19.20 EUR, difference 0.20 EUR\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WriteTaxDifferences() = %q, want %q", got, want)
+	}
+}
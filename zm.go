@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// zmTypes maps the categories reported in the Zusammenfassende Meldung to the "Art der Leistung".
+var zmTypes = map[Category]string{
+	IntraCommunityDelivery: "L", // innergemeinschaftliche Warenlieferungen
+	Triangular:             "D", // innergemeinschaftliche Dreiecksgeschäfte
+	EUService:              "S", // sonstige Leistungen
+}
+
+// ZMEntry is one line of the Zusammenfassende Meldung.
+type ZMEntry struct {
+	VatID  string
+	Type   string
+	Amount Cents
+}
+
+// zmVersion are the leading lines of the BZSt CSV format: the version of the format and
+// that the file contains no correction of an earlier ZM.
+var zmVersion = [][]string{{"#v1.0"}, {"#ve0"}}
+
+// invoiced returns a view of the JES file, where all receipts count with their invoice date.
+// This is always the case under Sollversteuerung, but the ZM is based on the invoice dates even
+// under Istversteuerung (§18a UStG).
+func (e *Eur) invoiced() *Eur {
+	inv := *e
+	inv.accrual = true
+	return &inv
+}
+
+// zmData sums up the intra-community transactions per customer VAT ID and type of transaction.
+// They are reported in the period of the invoice, not of the payment.
+func zmData(jes []*Eur, period Period) []ZMEntry {
+	type key struct {
+		vatID string
+		typ   string
+	}
+	sums := make(map[key]Cents)
+
+	for _, e := range jes {
+		for p, acc := range e.invoiced().taxPayments(period) {
+			vatKey, percent := e.classify(p, acc)
+			typ, ok := zmTypes[vatKey.Category]
+			if !ok {
+				continue
+			}
+
+			vatID := strings.ToUpper(strings.ReplaceAll(p.receipt.info.VatID, " ", ""))
+			if len(vatID) < 3 {
+				log.Fatalf("Receipt #%d in '%s' lacks the VAT ID of the customer. Please add it to '%s'.",
+					p.receipt.Number, e.file, sidecarName(e.file))
+			}
+
+			amount := p.getNetAmount(percent)
+			debug("ZM %s/%s (#%d):\t%s", vatID, typ, p.receipt.Number, amount)
+			sums[key{vatID, typ}] += amount
+		}
+	}
+
+	entries := make([]ZMEntry, 0, len(sums))
+	for _, k := range slices.SortedFunc(maps.Keys(sums), func(a, b key) int {
+		return cmp.Or(strings.Compare(a.vatID, b.vatID), strings.Compare(a.typ, b.typ))
+	}) {
+		if sums[k] != 0 {
+			entries = append(entries, ZMEntry{k.vatID, k.typ, sums[k]})
+		}
+	}
+	return entries
+}
+
+// WriteZM writes the Zusammenfassende Meldung in the CSV format of the BZSt upload.
+func WriteZM(w io.Writer, entries []ZMEntry) {
+	w = charmap.ISO8859_1.NewEncoder().Writer(w)
+
+	csvWriter := csv.NewWriter(w)
+	records := slices.Concat(zmVersion, [][]string{{"Laenderkennzeichen", "USt-IdNr.", "Betrag(EUR)", "Art der Leistung"}})
+	for _, e := range entries {
+		records = append(records, []string{e.VatID[:2], e.VatID[2:], e.Amount.EuroString(), e.Type})
+	}
+
+	if err := csvWriter.WriteAll(records); err != nil {
+		log.Fatalf("Writing ZM: %v", err)
+	}
+}
+
+func cmdZM(conf *Config, args []string) {
+	if len(args) < 2 {
+		log.Fatalf(usage, os.Args[0])
+	}
+
+	jesFiles, args := splitJesArgs(args)
+	periodStr := args[0]
+	period := periodArg(periodStr)
+
+	switch period.(type) {
+	case Month, Quarter:
+	default:
+		log.Fatalf("The ZM is filed per month or quarter, '%s' is not supported.", periodStr)
+	}
+
+	jes := loadJes(conf, jesFiles)
+	period = resolvePeriod(period, periodStr, jes)
+
+	WriteZM(os.Stdout, zmData(jes, period))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestZMData(t *testing.T) {
+	e := &Eur{accountInfo: map[TaxAccount]Account{520: {Number: 520}}}
+	e.prepareTaxFree([]TaxFreeRule{
+		{Account: 520, Kz: 41, BookingAccounts: []int{8125}},
+		{Account: 520, Kz: 21},
+	})
+	e.Receipts = []*Receipt{
+		{Number: 1, Date: Date{2024, 1, 10}, Paid: true, info: ReceiptInfo{VatID: "ATU 12345678"},
+			Payments: []*Payment{newPayment(520, 8338, "100.50")}},
+		// not yet paid, but reported with the invoice
+		{Number: 2, Date: Date{2024, 2, 10}, info: ReceiptInfo{VatID: "atu12345678"},
+			Payments: []*Payment{newPayment(520, 8338, "200")}},
+		{Number: 3, Date: Date{2024, 3, 10}, Paid: true, info: ReceiptInfo{VatID: "NL123456789B01"},
+			Payments: []*Payment{newPayment(520, 8125, "50")}},
+		{Number: 4, Date: Date{2024, 4, 10}, Paid: true, info: ReceiptInfo{VatID: "NL123456789B01"},
+			Payments: []*Payment{newPayment(520, 8125, "70")}},
+	}
+	e.Validate()
+
+	got := zmData([]*Eur{e}, Quarter{2024, 1})
+	want := []ZMEntry{
+		{"ATU12345678", "S", 30050},
+		{"NL123456789B01", "L", 5000},
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("zmData() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteZM(t *testing.T) {
+	var buf bytes.Buffer
+	WriteZM(&buf, []ZMEntry{
+		{"ATU12345678", "S", 30050},
+		{"NL123456789B01", "L", 5000},
+	})
+
+	got, err := charmap.ISO8859_1.NewDecoder().String(buf.String())
+	if err != nil {
+		t.Fatalf("Decoding output: %v", err)
+	}
+
+	// sample of the BZSt CSV format
+	want := "#v1.0\n" +
+		"#ve0\n" +
+		"Laenderkennzeichen,USt-IdNr.,Betrag(EUR),Art der Leistung\n" +
+		"AT,U12345678,300,S\n" +
+		"NL,123456789B01,50,L\n"
+	if got != want {
+		t.Errorf("WriteZM output = %q, want %q", got, want)
+	}
+}